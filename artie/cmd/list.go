@@ -0,0 +1,41 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package cmd
+
+import (
+	"github.com/gatblau/onix/artie/registry"
+	"github.com/spf13/cobra"
+)
+
+// listCmd lists the artefacts in the local registry.
+var listCmd = &cobra.Command{
+	Use:   "list",
+	Short: "list the artefacts in the local registry",
+	Args:  cobra.NoArgs,
+	Run:   runList,
+}
+
+var (
+	listQuiet  bool
+	listVerify bool
+)
+
+func init() {
+	listCmd.Flags().BoolVarP(&listQuiet, "quiet", "q", false, "only print artefact ids")
+	listCmd.Flags().BoolVar(&listVerify, "verify", false, "verify each artefact's stored files against its seal and report the outcome")
+	rootCmd.AddCommand(listCmd)
+}
+
+func runList(cmd *cobra.Command, args []string) {
+	reg := registry.NewFileRegistry()
+	if listQuiet {
+		reg.ListQ()
+		return
+	}
+	reg.List(listVerify)
+}
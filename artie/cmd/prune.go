@@ -0,0 +1,57 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/gatblau/onix/artie/core"
+	"github.com/gatblau/onix/artie/registry"
+	"github.com/spf13/cobra"
+)
+
+// pruneCmd runs a retention-policy-driven garbage collection sweep over the
+// local registry.
+var pruneCmd = &cobra.Command{
+	Use:   "prune",
+	Short: "remove tagged revisions, dangling artefacts and orphan blobs outside the retention policy",
+	Args:  cobra.NoArgs,
+	Run:   runPrune,
+}
+
+var (
+	pruneKeepLast        int
+	pruneKeepYoungerThan time.Duration
+	pruneKeepTagPatterns []string
+	pruneDryRun          bool
+	pruneForce           bool
+)
+
+func init() {
+	pruneCmd.Flags().IntVar(&pruneKeepLast, "keep-last", 0, "keep the N most recently created tagged revisions in each repository")
+	pruneCmd.Flags().DurationVar(&pruneKeepYoungerThan, "keep-younger-than", 0, "keep any tagged revision or dangling artefact created within this duration of now, e.g. 168h")
+	pruneCmd.Flags().StringArrayVar(&pruneKeepTagPatterns, "keep-tag", nil, "glob pattern matched against tags; matching tags are never removed (repeatable)")
+	pruneCmd.Flags().BoolVar(&pruneDryRun, "dry-run", false, "report what would be removed without changing anything")
+	pruneCmd.Flags().BoolVar(&pruneForce, "force", false, "allow pruning without any --keep-last, --keep-younger-than or --keep-tag set, which removes every tagged revision and dangling artefact in the registry")
+	rootCmd.AddCommand(pruneCmd)
+}
+
+func runPrune(cmd *cobra.Command, args []string) {
+	policy := registry.RetentionPolicy{
+		KeepLastN:       pruneKeepLast,
+		KeepYoungerThan: pruneKeepYoungerThan,
+		KeepTagPatterns: pruneKeepTagPatterns,
+		DryRun:          pruneDryRun,
+		Force:           pruneForce,
+	}
+	summary, err := registry.NewGC(registry.NewFileRegistry()).Prune(policy)
+	core.CheckErr(err, "failed to prune local registry")
+	fmt.Printf("tags removed: %d, artefacts removed: %d, orphan blobs removed: %d, bytes freed: %d\n",
+		summary.TagsRemoved, summary.ArtefactsRemoved, summary.OrphansRemoved, summary.BytesFreed)
+}
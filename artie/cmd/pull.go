@@ -0,0 +1,55 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package cmd
+
+import (
+	"github.com/gatblau/onix/artie/core"
+	"github.com/gatblau/onix/artie/registry"
+	"github.com/spf13/cobra"
+)
+
+// pullCmd pulls an artefact from a remote OCI compliant registry into the
+// local registry.
+var pullCmd = &cobra.Command{
+	Use:   "pull [repository:tag]",
+	Short: "pull an artefact from a remote registry",
+	Args:  cobra.ExactArgs(1),
+	Run:   runPull,
+}
+
+var (
+	pullRemote      string
+	pullCreds       string
+	pullInsecure    bool
+	pullQuiet       bool
+	pullChunkSize   int64
+	pullParallelism int
+)
+
+func init() {
+	pullCmd.Flags().StringVar(&pullRemote, "remote", "", "base URI of the remote registry, e.g. https://artregistry.gatblau.org")
+	pullCmd.Flags().StringVar(&pullCreds, "creds", "", "remote registry credentials in user:password form; defaults to the entry for --remote in ~/.artie/auth.json")
+	pullCmd.Flags().BoolVar(&pullInsecure, "insecure", false, "skip TLS certificate verification when talking to the remote registry")
+	pullCmd.Flags().BoolVar(&pullQuiet, "quiet", false, "suppress the progress reporter")
+	pullCmd.Flags().Int64Var(&pullChunkSize, "chunk-size", registry.DefaultChunkSize, "bytes requested per ranged GET when downloading the zip blob")
+	pullCmd.Flags().IntVar(&pullParallelism, "parallelism", registry.DefaultParallelism, "number of chunks downloaded concurrently")
+	rootCmd.AddCommand(pullCmd)
+}
+
+func runPull(cmd *cobra.Command, args []string) {
+	name, err := core.NewArtieName(args[0])
+	core.CheckErr(err, "invalid artefact reference: %s", args[0])
+	remote := &registry.OCIRemote{Uri: pullRemote}
+	opts := registry.TransferOptions{
+		Insecure:    pullInsecure,
+		Quiet:       pullQuiet,
+		ChunkSize:   pullChunkSize,
+		Parallelism: pullParallelism,
+	}
+	registry.NewFileRegistry().Pull(name, remote, pullCreds, opts)
+}
@@ -0,0 +1,55 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package cmd
+
+import (
+	"github.com/gatblau/onix/artie/core"
+	"github.com/gatblau/onix/artie/registry"
+	"github.com/spf13/cobra"
+)
+
+// pushCmd pushes an artefact from the local registry to a remote OCI
+// compliant registry.
+var pushCmd = &cobra.Command{
+	Use:   "push [repository:tag]",
+	Short: "push an artefact to a remote registry",
+	Args:  cobra.ExactArgs(1),
+	Run:   runPush,
+}
+
+var (
+	pushRemote      string
+	pushCreds       string
+	pushInsecure    bool
+	pushQuiet       bool
+	pushChunkSize   int64
+	pushParallelism int
+)
+
+func init() {
+	pushCmd.Flags().StringVar(&pushRemote, "remote", "", "base URI of the remote registry, e.g. https://artregistry.gatblau.org")
+	pushCmd.Flags().StringVar(&pushCreds, "creds", "", "remote registry credentials in user:password form; defaults to the entry for --remote in ~/.artie/auth.json")
+	pushCmd.Flags().BoolVar(&pushInsecure, "insecure", false, "skip TLS certificate verification when talking to the remote registry")
+	pushCmd.Flags().BoolVar(&pushQuiet, "quiet", false, "suppress the progress reporter")
+	pushCmd.Flags().Int64Var(&pushChunkSize, "chunk-size", registry.DefaultChunkSize, "not used by push, which uploads the zip blob in a single request")
+	pushCmd.Flags().IntVar(&pushParallelism, "parallelism", registry.DefaultParallelism, "not used by push, which uploads the zip blob in a single request")
+	rootCmd.AddCommand(pushCmd)
+}
+
+func runPush(cmd *cobra.Command, args []string) {
+	name, err := core.NewArtieName(args[0])
+	core.CheckErr(err, "invalid artefact reference: %s", args[0])
+	remote := &registry.OCIRemote{Uri: pushRemote}
+	opts := registry.TransferOptions{
+		Insecure:    pushInsecure,
+		Quiet:       pushQuiet,
+		ChunkSize:   pushChunkSize,
+		Parallelism: pushParallelism,
+	}
+	registry.NewFileRegistry().Push(name, remote, pushCreds, opts)
+}
@@ -0,0 +1,53 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/gatblau/onix/artie/core"
+	"github.com/gatblau/onix/artie/registry"
+	"github.com/spf13/cobra"
+)
+
+// verifyCmd checks an artefact's stored zip blob against the per-entry
+// digests recorded in its seal, detecting tampering or bit-rot at the file
+// level.
+var verifyCmd = &cobra.Command{
+	Use:   "verify [repository:tag]",
+	Short: "verify an artefact's stored files against its seal",
+	Args:  cobra.ExactArgs(1),
+	Run:   runVerify,
+}
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+}
+
+func runVerify(cmd *cobra.Command, args []string) {
+	name, err := core.NewArtieName(args[0])
+	core.CheckErr(err, "invalid artefact reference: %s", args[0])
+	result, err := registry.NewFileRegistry().Verify(name)
+	core.CheckErr(err, "failed to verify artefact")
+	if result.Ok {
+		fmt.Printf("%s: OK\n", result.Name)
+		return
+	}
+	fmt.Printf("%s: FAILED\n", result.Name)
+	for _, e := range result.Mismatched {
+		fmt.Printf("  mismatched: %s\n", e)
+	}
+	for _, e := range result.Missing {
+		fmt.Printf("  missing: %s\n", e)
+	}
+	for _, e := range result.Extra {
+		fmt.Printf("  extra: %s\n", e)
+	}
+	os.Exit(1)
+}
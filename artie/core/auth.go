@@ -0,0 +1,57 @@
+/*
+  Onix ServerConfig Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package core
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// authConfig mirrors the layout of Docker's config.json, so existing tooling
+// that manages per-host credentials is familiar to Artie users.
+type authConfig struct {
+	Auths map[string]struct {
+		Auth string `json:"auth"`
+	} `json:"auths"`
+}
+
+// authFile is the fully qualified name of the Artie credentials file.
+func authFile() string {
+	return filepath.Join(HomeDir(), fmt.Sprintf(".%s", CliName), "auth.json")
+}
+
+// HostCredentials looks up the "user:password" credentials configured for
+// host in ~/.artie/auth.json, returning an empty string if none are found or
+// the file does not exist.
+func HostCredentials(host string) (string, error) {
+	bytes, err := ioutil.ReadFile(authFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", err
+	}
+	var cfg authConfig
+	if err = json.Unmarshal(bytes, &cfg); err != nil {
+		return "", fmt.Errorf("failed to parse %s: %w", authFile(), err)
+	}
+	entry, ok := cfg.Auths[host]
+	if !ok {
+		return "", nil
+	}
+	decoded, err := base64.StdEncoding.DecodeString(entry.Auth)
+	if err != nil {
+		return "", fmt.Errorf("invalid credentials for host %s: %w", host, err)
+	}
+	return strings.TrimSpace(string(decoded)), nil
+}
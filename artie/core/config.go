@@ -0,0 +1,62 @@
+/*
+  Onix ServerConfig Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package core
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Config holds the settings read from ~/.artie/config.yaml.
+type Config struct {
+	// BlobStore is the URL of the backend used to store artefact zip and
+	// seal blobs, e.g. "file:///home/user/.artie/blobs", "s3://bucket/prefix",
+	// "gs://bucket/prefix" or "az://container/prefix". Defaults to a local
+	// file:// store rooted at the "blobs" sub-directory of RegistryPath()
+	// when not set (see BlobStoreUri).
+	BlobStore string `yaml:"blob_store"`
+}
+
+// configFile is the fully qualified name of the Artie configuration file.
+func configFile() string {
+	return filepath.Join(HomeDir(), fmt.Sprintf(".%s", CliName), "config.yaml")
+}
+
+// LoadConfig reads ~/.artie/config.yaml, returning a zero-value Config (and no
+// error) if the file does not exist.
+func LoadConfig() (*Config, error) {
+	cfg := &Config{}
+	bytes, err := ioutil.ReadFile(configFile())
+	if err != nil {
+		if os.IsNotExist(err) {
+			return cfg, nil
+		}
+		return nil, err
+	}
+	if err = yaml.Unmarshal(bytes, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", configFile(), err)
+	}
+	return cfg, nil
+}
+
+// BlobStoreUri returns the configured blob store URL, defaulting to a local
+// file:// store rooted at a "blobs" sub-directory of RegistryPath() when not
+// set in config.yaml. The blobs live in their own sub-directory, rather than
+// directly under RegistryPath(), so that code enumerating every stored blob
+// (e.g. registry.GC) never walks over repository.json, its lock file, or
+// config.yaml/auth.json, which share that same directory.
+func (c *Config) BlobStoreUri() string {
+	if len(c.BlobStore) > 0 {
+		return c.BlobStore
+	}
+	return fmt.Sprintf("file://%s", filepath.Join(RegistryPath(), "blobs"))
+}
@@ -0,0 +1,138 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package core
+
+import (
+	"archive/zip"
+	"io/ioutil"
+	"os"
+	"testing"
+)
+
+// writeTestZip creates a zip file at path containing entries, a map of entry
+// name to body content.
+func writeTestZip(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	zw := zip.NewWriter(f)
+	for name, body := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write([]byte(body)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestZipEntryDigestsDetectsTamper(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artie-digest-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	original := dir + "/original.zip"
+	writeTestZip(t, original, map[string]string{
+		"a.txt": "hello",
+		"b.txt": "world",
+	})
+	before, err := ZipEntryDigests(original)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(before) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(before))
+	}
+
+	tampered := dir + "/tampered.zip"
+	writeTestZip(t, tampered, map[string]string{
+		"a.txt": "hello",
+		"b.txt": "WORLD", // tampered content
+	})
+	after, err := ZipEntryDigests(tampered)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	digestFor := func(entries []EntryDigest, name string) string {
+		for _, e := range entries {
+			if e.Name == name {
+				return e.Digest
+			}
+		}
+		t.Fatalf("entry %s not found", name)
+		return ""
+	}
+	if digestFor(before, "a.txt") != digestFor(after, "a.txt") {
+		t.Errorf("untouched entry a.txt should have a stable digest")
+	}
+	if digestFor(before, "b.txt") == digestFor(after, "b.txt") {
+		t.Errorf("tampered entry b.txt should have a different digest")
+	}
+}
+
+func TestZipEntryDigestsOrderIndependent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artie-digest-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// the same entries, written in reverse order
+	first := dir + "/first.zip"
+	f, err := os.Create(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(f)
+	for _, name := range []string{"a.txt", "b.txt"} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(name))
+	}
+	zw.Close()
+	f.Close()
+
+	second := dir + "/second.zip"
+	f, err = os.Create(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw = zip.NewWriter(f)
+	for _, name := range []string{"b.txt", "a.txt"} {
+		w, _ := zw.Create(name)
+		w.Write([]byte(name))
+	}
+	zw.Close()
+	f.Close()
+
+	d1, err := ZipEntryDigests(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d2, err := ZipEntryDigests(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(d1) != len(d2) {
+		t.Fatalf("expected the same number of entries regardless of pack order")
+	}
+	for i := range d1 {
+		if d1[i] != d2[i] {
+			t.Errorf("expected entry %d to match regardless of pack order: %+v != %+v", i, d1[i], d2[i])
+		}
+	}
+}
@@ -0,0 +1,143 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+
+// Package reference parses artefact references using a grammar modelled on
+// Docker's distribution reference format (domain/path[:tag][@digest]), so
+// that a short form typed on the command line (e.g. "hello:v1") and its
+// fully qualified equivalent (e.g.
+// "registry.gatblau.org/library/hello:v1") resolve to the same artefact.
+package reference
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// DefaultDomain is used when a reference has no domain component.
+	DefaultDomain = "registry.gatblau.org"
+	// DefaultNamespace is used when a reference's path has no namespace
+	// (i.e. no slash), mirroring Docker Hub's implicit "library/" prefix.
+	DefaultNamespace = "library"
+	// DefaultTag is used when a reference has neither a tag nor a digest.
+	DefaultTag = "latest"
+)
+
+// Reference is a parsed artefact reference: a domain, a slash-separated
+// path, and an optional tag and/or digest.
+type Reference struct {
+	Domain string
+	Path   string
+	Tag    string
+	Digest string
+}
+
+// Parse parses s into a Reference, applying the default domain, namespace
+// and tag where they are omitted.
+func Parse(s string) (*Reference, error) {
+	if len(s) == 0 {
+		return nil, fmt.Errorf("reference: cannot parse empty string")
+	}
+	remainder := s
+	digest := ""
+	if i := strings.Index(remainder, "@"); i != -1 {
+		digest = remainder[i+1:]
+		remainder = remainder[:i]
+		if digest == "" {
+			return nil, fmt.Errorf("reference: invalid digest in %q", s)
+		}
+	}
+	tag := ""
+	// the tag separator is the last colon found after the last slash, so a
+	// port number in the domain (e.g. "localhost:5000/name") is never
+	// mistaken for a tag
+	lastSlash := strings.LastIndex(remainder, "/")
+	if i := strings.LastIndex(remainder, ":"); i != -1 && i > lastSlash {
+		tag = remainder[i+1:]
+		remainder = remainder[:i]
+		if tag == "" {
+			return nil, fmt.Errorf("reference: invalid tag in %q", s)
+		}
+	}
+	if remainder == "" {
+		return nil, fmt.Errorf("reference: missing name in %q", s)
+	}
+	if digest == "" && tag == "" {
+		tag = DefaultTag
+	}
+	domain, path := splitDomain(remainder)
+	return &Reference{
+		Domain: domain,
+		Path:   path,
+		Tag:    tag,
+		Digest: digest,
+	}, nil
+}
+
+// splitDomain separates name's leading domain component, if any, from its
+// path, applying DefaultDomain and DefaultNamespace where they are omitted.
+// A component is treated as a domain when it contains a "." or ":" or is
+// "localhost", matching Docker's own heuristic.
+func splitDomain(name string) (domain, path string) {
+	parts := strings.SplitN(name, "/", 2)
+	if len(parts) == 2 && isDomain(parts[0]) {
+		return strings.ToLower(parts[0]), normalizePath(parts[1])
+	}
+	return DefaultDomain, normalizePath(name)
+}
+
+func isDomain(s string) bool {
+	return s == "localhost" || strings.ContainsAny(s, ".:")
+}
+
+func normalizePath(path string) string {
+	if strings.Contains(path, "/") {
+		return path
+	}
+	return DefaultNamespace + "/" + path
+}
+
+// CanonicalName returns the fully qualified domain/path, with no tag or
+// digest, used as the repository key in the local registry.
+func (r *Reference) CanonicalName() string {
+	return fmt.Sprintf("%s/%s", r.Domain, r.Path)
+}
+
+// Canonical returns the fully qualified reference: domain/path, followed by
+// the digest when known, otherwise the tag.
+func (r *Reference) Canonical() string {
+	ref := r.CanonicalName()
+	if r.Digest != "" {
+		return ref + "@" + r.Digest
+	}
+	if r.Tag != "" {
+		return ref + ":" + r.Tag
+	}
+	return ref
+}
+
+// Familiar returns the short, user-facing form of the reference: the
+// default domain and namespace are dropped when they match the defaults,
+// and the tag is dropped when it is DefaultTag, so
+// "registry.gatblau.org/library/hello:latest" becomes simply "hello".
+func (r *Reference) Familiar() string {
+	path := r.Path
+	ref := path
+	if r.Domain == DefaultDomain {
+		ref = strings.TrimPrefix(path, DefaultNamespace+"/")
+	} else {
+		ref = r.Domain + "/" + path
+	}
+	if r.Digest != "" {
+		return ref + "@" + r.Digest
+	}
+	if r.Tag != "" && r.Tag != DefaultTag {
+		return ref + ":" + r.Tag
+	}
+	return ref
+}
@@ -0,0 +1,110 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package reference
+
+import "testing"
+
+func TestParseTagOnly(t *testing.T) {
+	ref, err := Parse("group/hello:v1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ref.Domain != DefaultDomain {
+		t.Errorf("expected domain %q, got %q", DefaultDomain, ref.Domain)
+	}
+	if ref.Path != "group/hello" {
+		t.Errorf("expected path %q, got %q", "group/hello", ref.Path)
+	}
+	if ref.Tag != "v1" {
+		t.Errorf("expected tag %q, got %q", "v1", ref.Tag)
+	}
+	if ref.Digest != "" {
+		t.Errorf("expected no digest, got %q", ref.Digest)
+	}
+}
+
+func TestParseDigestOnly(t *testing.T) {
+	const digest = "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b85"
+	ref, err := Parse("group/hello@" + digest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ref.Digest != digest {
+		t.Errorf("expected digest %q, got %q", digest, ref.Digest)
+	}
+	if ref.Tag != "" {
+		t.Errorf("expected no tag, got %q", ref.Tag)
+	}
+}
+
+func TestParseTagAndDigest(t *testing.T) {
+	const digest = "sha256:e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b85"
+	ref, err := Parse("group/hello:v1@" + digest)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ref.Tag != "v1" {
+		t.Errorf("expected tag %q, got %q", "v1", ref.Tag)
+	}
+	if ref.Digest != digest {
+		t.Errorf("expected digest %q, got %q", digest, ref.Digest)
+	}
+	// the digest, being the more specific identifier, takes precedence in
+	// the canonical form
+	if canonical := ref.Canonical(); canonical != ref.CanonicalName()+"@"+digest {
+		t.Errorf("expected canonical form to prefer digest, got %q", canonical)
+	}
+}
+
+func TestParseMissingNamespace(t *testing.T) {
+	ref, err := Parse("hello:v1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ref.Path != DefaultNamespace+"/hello" {
+		t.Errorf("expected default namespace to be applied, got path %q", ref.Path)
+	}
+	if familiar := ref.Familiar(); familiar != "hello:v1" {
+		t.Errorf("expected familiar form to drop the default namespace, got %q", familiar)
+	}
+}
+
+func TestParseIDNUppercaseDomain(t *testing.T) {
+	ref, err := Parse("Registry.Example.COM/group/hello:v1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ref.Domain != "registry.example.com" {
+		t.Errorf("expected domain to be lower-cased, got %q", ref.Domain)
+	}
+	const want = "registry.example.com/group/hello"
+	if ref.CanonicalName() != want {
+		t.Errorf("expected canonical name %q, got %q", want, ref.CanonicalName())
+	}
+	// an internationalized domain name is lower-cased the same way
+	idn, err := Parse("Bücher.Example/group/hello:v1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if idn.Domain != "bücher.example" {
+		t.Errorf("expected IDN domain to be lower-cased, got %q", idn.Domain)
+	}
+}
+
+func TestFamiliarRoundTrip(t *testing.T) {
+	ref, err := Parse("hello")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if ref.Tag != DefaultTag {
+		t.Errorf("expected default tag %q, got %q", DefaultTag, ref.Tag)
+	}
+	if familiar := ref.Familiar(); familiar != "hello" {
+		t.Errorf("expected the default tag to be dropped from the familiar form, got %q", familiar)
+	}
+}
@@ -0,0 +1,45 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package core
+
+import (
+	"encoding/json"
+	"io/ioutil"
+)
+
+// Manifest describes the artefact a Seal certifies.
+type Manifest struct {
+	// Type is the application type packaged in the artefact.
+	Type string `json:"type"`
+	// Size is the human readable size of the artefact zip file.
+	Size string `json:"size"`
+	// Time is the RFC850 creation timestamp of the artefact.
+	Time string `json:"time"`
+	// Entries is the per zip-entry digest list computed at Add time, used
+	// to detect tampering or bit-rot at the file level. Populated by
+	// FileRegistry.Add; empty for seals created before that field existed.
+	Entries []EntryDigest `json:"entries,omitempty"`
+}
+
+// Seal certifies the content and provenance of an artefact.
+type Seal struct {
+	Manifest Manifest `json:"manifest"`
+}
+
+// NewSealFromFile reads and parses the seal JSON file at filename.
+func NewSealFromFile(filename string) (*Seal, error) {
+	bytes, err := ioutil.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	s := &Seal{}
+	if err := json.Unmarshal(bytes, s); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
@@ -0,0 +1,97 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+
+	"github.com/Azure/azure-storage-blob-go/azblob"
+)
+
+// AzureStorage stores blobs in an Azure Blob Storage container, selected with
+// the az:// URL scheme, e.g. az://my-container/artie/blobs. Credentials are
+// taken from the AZURE_STORAGE_ACCOUNT and AZURE_STORAGE_ACCESS_KEY
+// environment variables, following the Azure CLI convention.
+type AzureStorage struct {
+	Container string
+	Prefix    string
+	url       azblob.ContainerURL
+}
+
+// NewAzureStorage creates an AzureStorage for container, storing objects
+// under prefix.
+func NewAzureStorage(account, accountKey, container, prefix string) (*AzureStorage, error) {
+	credential, err := azblob.NewSharedKeyCredential(account, accountKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create azure credential: %w", err)
+	}
+	pipeline := azblob.NewPipeline(credential, azblob.PipelineOptions{})
+	containerURL, err := url.Parse(fmt.Sprintf("https://%s.blob.core.windows.net/%s", account, container))
+	if err != nil {
+		return nil, err
+	}
+	return &AzureStorage{Container: container, Prefix: prefix, url: azblob.NewContainerURL(*containerURL, pipeline)}, nil
+}
+
+func (s *AzureStorage) objectKey(key string) string {
+	return strings.Trim(s.Prefix, "/") + "/" + key
+}
+
+func (s *AzureStorage) blob(key string) azblob.BlockBlobURL {
+	return s.url.NewBlockBlobURL(s.objectKey(key))
+}
+
+func (s *AzureStorage) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	_, err := azblob.UploadStreamToBlockBlob(ctx, r, s.blob(key), azblob.UploadStreamToBlockBlobOptions{})
+	return err
+}
+
+func (s *AzureStorage) Get(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	resp, err := s.blob(key).Download(ctx, 0, azblob.CountToEnd, azblob.BlobAccessConditions{}, false, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		return nil, 0, err
+	}
+	return resp.Body(azblob.RetryReaderOptions{}), resp.ContentLength(), nil
+}
+
+func (s *AzureStorage) Stat(ctx context.Context, key string) (int64, error) {
+	props, err := s.blob(key).GetProperties(ctx, azblob.BlobAccessConditions{}, azblob.ClientProvidedKeyOptions{})
+	if err != nil {
+		return 0, err
+	}
+	return props.ContentLength(), nil
+}
+
+// Delete removes the blob stored under key. As with the other backends, it
+// is not an error to delete a key that no longer exists.
+func (s *AzureStorage) Delete(ctx context.Context, key string) error {
+	_, err := s.blob(key).Delete(ctx, azblob.DeleteSnapshotsOptionNone, azblob.BlobAccessConditions{})
+	if stgErr, ok := err.(azblob.StorageError); ok && stgErr.ServiceCode() == azblob.ServiceCodeBlobNotFound {
+		return nil
+	}
+	return err
+}
+
+func (s *AzureStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	keys := make([]string, 0)
+	for marker := (azblob.Marker{}); marker.NotDone(); {
+		resp, err := s.url.ListBlobsFlatSegment(ctx, marker, azblob.ListBlobsSegmentOptions{Prefix: s.objectKey(prefix)})
+		if err != nil {
+			return nil, err
+		}
+		for _, item := range resp.Segment.BlobItems {
+			keys = append(keys, strings.TrimPrefix(item.Name, strings.Trim(s.Prefix, "/")+"/"))
+		}
+		marker = resp.NextMarker
+	}
+	return keys, nil
+}
@@ -0,0 +1,92 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"cloud.google.com/go/storage"
+	"google.golang.org/api/iterator"
+)
+
+// GCSStorage stores blobs in a Google Cloud Storage bucket, selected with the
+// gs:// URL scheme, e.g. gs://my-bucket/artie/blobs.
+type GCSStorage struct {
+	Bucket string
+	Prefix string
+	client *storage.Client
+}
+
+// NewGCSStorage creates a GCSStorage for bucket, storing objects under prefix.
+func NewGCSStorage(ctx context.Context, bucket, prefix string) (*GCSStorage, error) {
+	client, err := storage.NewClient(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcs client: %w", err)
+	}
+	return &GCSStorage{Bucket: bucket, Prefix: prefix, client: client}, nil
+}
+
+func (s *GCSStorage) objectKey(key string) string {
+	return strings.Trim(s.Prefix, "/") + "/" + key
+}
+
+func (s *GCSStorage) object(key string) *storage.ObjectHandle {
+	return s.client.Bucket(s.Bucket).Object(s.objectKey(key))
+}
+
+func (s *GCSStorage) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	w := s.object(key).NewWriter(ctx)
+	if _, err := io.Copy(w, r); err != nil {
+		w.Close()
+		return err
+	}
+	return w.Close()
+}
+
+func (s *GCSStorage) Get(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	r, err := s.object(key).NewReader(ctx)
+	if err != nil {
+		return nil, 0, err
+	}
+	return r, r.Attrs.Size, nil
+}
+
+func (s *GCSStorage) Stat(ctx context.Context, key string) (int64, error) {
+	attrs, err := s.object(key).Attrs(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return attrs.Size, nil
+}
+
+func (s *GCSStorage) Delete(ctx context.Context, key string) error {
+	err := s.object(key).Delete(ctx)
+	if err == storage.ErrObjectNotExist {
+		return nil
+	}
+	return err
+}
+
+func (s *GCSStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	it := s.client.Bucket(s.Bucket).Objects(ctx, &storage.Query{Prefix: s.objectKey(prefix)})
+	keys := make([]string, 0)
+	for {
+		attrs, err := it.Next()
+		if err == iterator.Done {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, strings.TrimPrefix(attrs.Name, strings.Trim(s.Prefix, "/")+"/"))
+	}
+	return keys, nil
+}
@@ -0,0 +1,110 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+)
+
+// LocalStorage stores blobs as plain files under Root, selected with the
+// file:// URL scheme. It is the default backend, matching Artie's original
+// behaviour of keeping everything under core.RegistryPath().
+type LocalStorage struct {
+	Root string
+}
+
+// NewLocalStorage creates a LocalStorage rooted at root, creating the
+// directory if it does not already exist.
+func NewLocalStorage(root string) (*LocalStorage, error) {
+	if err := os.MkdirAll(root, 0755); err != nil {
+		return nil, err
+	}
+	return &LocalStorage{Root: root}, nil
+}
+
+func (s *LocalStorage) path(key string) string {
+	return filepath.Join(s.Root, key)
+}
+
+func (s *LocalStorage) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	path := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = io.Copy(f, r)
+	return err
+}
+
+func (s *LocalStorage) Get(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	info, err := os.Stat(s.path(key))
+	if err != nil {
+		return nil, 0, err
+	}
+	f, err := os.Open(s.path(key))
+	if err != nil {
+		return nil, 0, err
+	}
+	return f, info.Size(), nil
+}
+
+func (s *LocalStorage) Stat(ctx context.Context, key string) (int64, error) {
+	info, err := os.Stat(s.path(key))
+	if err != nil {
+		return 0, err
+	}
+	return info.Size(), nil
+}
+
+func (s *LocalStorage) Delete(ctx context.Context, key string) error {
+	err := os.Remove(s.path(key))
+	if err != nil && os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
+
+func (s *LocalStorage) List(ctx context.Context, prefix string) ([]string, error) {
+	entries, err := ioutil.ReadDir(s.Root)
+	if err != nil {
+		return nil, err
+	}
+	keys := make([]string, 0)
+	for _, e := range entries {
+		if e.IsDir() {
+			continue
+		}
+		if prefix == "" || len(e.Name()) >= len(prefix) && e.Name()[:len(prefix)] == prefix {
+			keys = append(keys, e.Name())
+		}
+	}
+	return keys, nil
+}
+
+// Rename moves a local file directly into the store without a copy, used by
+// callers that already have the blob staged on local disk (e.g. a build
+// output directory) and want to avoid a redundant read/write cycle.
+func (s *LocalStorage) Rename(srcPath, key string) error {
+	dst := s.path(key)
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return err
+	}
+	if err := os.Rename(srcPath, dst); err != nil {
+		return fmt.Errorf("failed to move %s into local blob storage: %w", srcPath, err)
+	}
+	return nil
+}
@@ -0,0 +1,75 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package blob
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLocalStoragePutGetDelete(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artie-blob-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewLocalStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	if err := s.Put(ctx, "a.zip", strings.NewReader("content"), 7); err != nil {
+		t.Fatalf("Put failed: %v", err)
+	}
+	rc, size, err := s.Get(ctx, "a.zip")
+	if err != nil {
+		t.Fatalf("Get failed: %v", err)
+	}
+	defer rc.Close()
+	if size != 7 {
+		t.Errorf("expected size 7, got %d", size)
+	}
+
+	keys, err := s.List(ctx, "")
+	if err != nil {
+		t.Fatalf("List failed: %v", err)
+	}
+	if len(keys) != 1 || keys[0] != "a.zip" {
+		t.Errorf("expected [a.zip], got %v", keys)
+	}
+
+	if err := s.Delete(ctx, "a.zip"); err != nil {
+		t.Fatalf("Delete failed: %v", err)
+	}
+	if _, _, err := s.Get(ctx, "a.zip"); err == nil {
+		t.Errorf("expected Get to fail after Delete")
+	}
+}
+
+// TestLocalStorageDeleteIdempotent ensures deleting a key that was never
+// written, or was already deleted, is not an error, matching the other
+// backends (S3 is naturally idempotent, GCS and Azure explicitly swallow
+// "not found").
+func TestLocalStorageDeleteIdempotent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artie-blob-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, err := NewLocalStorage(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Delete(context.Background(), "missing.zip"); err != nil {
+		t.Errorf("expected deleting a missing key to succeed, got %v", err)
+	}
+}
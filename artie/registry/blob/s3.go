@@ -0,0 +1,95 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package blob
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go/aws"
+	"github.com/aws/aws-sdk-go/aws/session"
+	"github.com/aws/aws-sdk-go/service/s3"
+	"github.com/aws/aws-sdk-go/service/s3/s3manager"
+)
+
+// S3Storage stores blobs in an AWS S3 bucket, selected with the s3:// URL
+// scheme, e.g. s3://my-bucket/artie/blobs.
+type S3Storage struct {
+	Bucket string
+	Prefix string
+	client *s3.S3
+}
+
+// NewS3Storage creates an S3Storage for bucket, storing objects under prefix.
+func NewS3Storage(bucket, prefix string) (*S3Storage, error) {
+	sess, err := session.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create aws session: %w", err)
+	}
+	return &S3Storage{Bucket: bucket, Prefix: prefix, client: s3.New(sess)}, nil
+}
+
+func (s *S3Storage) objectKey(key string) string {
+	return strings.Trim(s.Prefix, "/") + "/" + key
+}
+
+func (s *S3Storage) Put(ctx context.Context, key string, r io.Reader, size int64) error {
+	uploader := s3manager.NewUploaderWithClient(s.client)
+	_, err := uploader.UploadWithContext(ctx, &s3manager.UploadInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.objectKey(key)),
+		Body:   r,
+	})
+	return err
+}
+
+func (s *S3Storage) Get(ctx context.Context, key string) (io.ReadCloser, int64, error) {
+	out, err := s.client.GetObjectWithContext(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return out.Body, aws.Int64Value(out.ContentLength), nil
+}
+
+func (s *S3Storage) Stat(ctx context.Context, key string) (int64, error) {
+	out, err := s.client.HeadObjectWithContext(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	if err != nil {
+		return 0, err
+	}
+	return aws.Int64Value(out.ContentLength), nil
+}
+
+func (s *S3Storage) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObjectWithContext(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.Bucket),
+		Key:    aws.String(s.objectKey(key)),
+	})
+	return err
+}
+
+func (s *S3Storage) List(ctx context.Context, prefix string) ([]string, error) {
+	var keys []string
+	err := s.client.ListObjectsV2PagesWithContext(ctx, &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.Bucket),
+		Prefix: aws.String(s.objectKey(prefix)),
+	}, func(page *s3.ListObjectsV2Output, lastPage bool) bool {
+		for _, obj := range page.Contents {
+			keys = append(keys, strings.TrimPrefix(aws.StringValue(obj.Key), strings.Trim(s.Prefix, "/")+"/"))
+		}
+		return true
+	})
+	return keys, err
+}
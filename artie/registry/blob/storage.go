@@ -0,0 +1,49 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+
+// Package blob abstracts the storage of artefact zip and seal blobs away from
+// the FileRegistry metadata layer, so a team can keep repository.json on
+// local disk while the (potentially large) blobs it references live in
+// object storage.
+package blob
+
+import (
+	"context"
+	"io"
+)
+
+// Storage is implemented by every supported blob backend. Keys are opaque,
+// slash-free object names (e.g. "<artefact-id>.zip") relative to the
+// backend's configured root/prefix.
+type Storage interface {
+	// Put stores size bytes read from r under key, overwriting any existing
+	// object with the same key.
+	Put(ctx context.Context, key string, r io.Reader, size int64) error
+
+	// Get opens the object stored under key for reading, along with its size.
+	// The caller is responsible for closing the returned reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, int64, error)
+
+	// Stat returns the size in bytes of the object stored under key.
+	Stat(ctx context.Context, key string) (int64, error)
+
+	// Delete removes the object stored under key. It is not an error to
+	// delete a key that does not exist.
+	Delete(ctx context.Context, key string) error
+
+	// List returns every key stored under the given prefix.
+	List(ctx context.Context, prefix string) ([]string, error)
+}
+
+// Renamer is implemented by backends that can adopt a file already staged on
+// local disk without a redundant read/write cycle (LocalStorage only --
+// remote backends always require an upload). Callers should type-assert for
+// it and fall back to Put when it is not available.
+type Renamer interface {
+	Rename(srcPath, key string) error
+}
@@ -0,0 +1,49 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package blob
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// Open selects and configures a Storage backend from a URL read from
+// ~/.artie/config.yaml:
+//   - file:///path/to/dir             local disk (the default)
+//   - s3://bucket/prefix              AWS S3
+//   - gs://bucket/prefix              Google Cloud Storage
+//   - az://container/prefix           Azure Blob Storage
+func Open(ctx context.Context, rawURL string) (Storage, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid blob storage url %q: %w", rawURL, err)
+	}
+	prefix := strings.Trim(u.Path, "/")
+	switch u.Scheme {
+	case "", "file":
+		root := u.Path
+		if u.Host != "" {
+			// a bare "file://relative/path" parses the first segment as Host
+			root = u.Host + u.Path
+		}
+		return NewLocalStorage(root)
+	case "s3":
+		return NewS3Storage(u.Host, prefix)
+	case "gs":
+		return NewGCSStorage(ctx, u.Host, prefix)
+	case "az":
+		account := os.Getenv("AZURE_STORAGE_ACCOUNT")
+		key := os.Getenv("AZURE_STORAGE_ACCESS_KEY")
+		return NewAzureStorage(account, key, u.Host, prefix)
+	default:
+		return nil, fmt.Errorf("unsupported blob storage scheme %q", u.Scheme)
+	}
+}
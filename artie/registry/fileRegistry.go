@@ -8,11 +8,17 @@
 package registry
 
 import (
+	"context"
+	"crypto/sha256"
 	"crypto/tls"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/gatblau/onix/artie/core"
+	"github.com/gatblau/onix/artie/core/reference"
+	"github.com/gatblau/onix/artie/registry/blob"
+	"io"
 	"io/ioutil"
 	"log"
 	"math"
@@ -28,13 +34,31 @@ import (
 // the default local registry implemented as a file system
 type FileRegistry struct {
 	Repositories []*repository `json:"repositories"`
+	// store holds the actual zip and seal blobs; repository.json metadata
+	// always stays on local disk regardless of which backend this points to
+	store blob.Storage
+	// root overrides core.RegistryPath() when set, so tests can point a
+	// FileRegistry at an isolated directory instead of the user's real
+	// ~/.artie. Empty means use the default.
+	root string
+}
+
+// canonicalRepositoryName normalizes raw (a fully qualified or familiar,
+// short-hand reference) to its canonical domain/path form, applying the
+// default domain, namespace and tag where they are omitted, so lookups do
+// not require the exact string a repository happens to be stored under.
+func canonicalRepositoryName(raw string) string {
+	ref, err := reference.Parse(raw)
+	core.CheckErr(err, "invalid artefact reference: %s", raw)
+	return ref.CanonicalName()
 }
 
 // find the repository specified by name
 func (r *FileRegistry) findRepository(name *core.ArtieName) *repository {
+	canonical := canonicalRepositoryName(name.FullyQualifiedName())
 	// find repository using artefact name
 	for _, repository := range r.Repositories {
-		if repository.Repository == name.FullyQualifiedName() {
+		if canonicalRepositoryName(repository.Repository) == canonical {
 			return repository
 		}
 	}
@@ -51,9 +75,10 @@ func (r *FileRegistry) findRepository(name *core.ArtieName) *repository {
 
 // return all the artefacts within the same repository
 func (r *FileRegistry) GetArtefactsByName(name *core.ArtieName) ([]*artefact, bool) {
+	canonical := canonicalRepositoryName(name.FullyQualifiedName())
 	var artefacts = make([]*artefact, 0)
 	for _, repository := range r.Repositories {
-		if repository.Repository == name.FullyQualifiedName() {
+		if canonicalRepositoryName(repository.Repository) == canonical {
 			for _, artefact := range repository.Artefacts {
 				artefacts = append(artefacts, artefact)
 			}
@@ -73,10 +98,11 @@ func (r *FileRegistry) GetArtefactsByName(name *core.ArtieName) ([]*artefact, bo
 func (r *FileRegistry) GetArtefact(name *core.ArtieName) *artefact {
 	// go through the artefacts in the repository and check for Id matches
 	artefactsFound := make([]*artefact, 0)
+	canonical := canonicalRepositoryName(name.FullyQualifiedName())
 
 	// first gets the repository the artefact is in
 	for _, repository := range r.Repositories {
-		if repository.Repository == name.FullyQualifiedName() {
+		if canonicalRepositoryName(repository.Repository) == canonical {
 			// try and get it by id first
 			for _, artefact := range repository.Artefacts {
 				for _, tag := range artefact.Tags {
@@ -142,11 +168,35 @@ func NewFileRegistry() *FileRegistry {
 	}
 	// load local registry
 	r.load()
+	// configure the blob storage backend (local disk unless overridden in
+	// ~/.artie/config.yaml)
+	cfg, err := core.LoadConfig()
+	core.CheckErr(err, "failed to load artie configuration")
+	store, err := blob.Open(context.Background(), cfg.BlobStoreUri())
+	core.CheckErr(err, "failed to open blob storage backend")
+	r.store = store
 	return r
 }
 
+// newFileRegistryAt creates a FileRegistry rooted at dir instead of the
+// default core.RegistryPath(), with blobs stored in a "blobs" sub-directory
+// of dir, matching the layout NewFileRegistry uses by default. Used by tests
+// that need a FileRegistry isolated from the user's real ~/.artie.
+func newFileRegistryAt(dir string) (*FileRegistry, error) {
+	store, err := blob.Open(context.Background(), fmt.Sprintf("file://%s", filepath.Join(dir, "blobs")))
+	if err != nil {
+		return nil, err
+	}
+	r := &FileRegistry{Repositories: []*repository{}, store: store, root: dir}
+	r.load()
+	return r, nil
+}
+
 // the local Path to the local FileRegistry
 func (r *FileRegistry) Path() string {
+	if len(r.root) > 0 {
+		return r.root
+	}
 	return core.RegistryPath()
 }
 
@@ -184,6 +234,14 @@ func (r *FileRegistry) load() {
 // Add the artefact and seal to the FileRegistry
 func (r *FileRegistry) Add(filename string, name *core.ArtieName, s *core.Seal) {
 	core.Msg("adding artefact to local registry: %s", name)
+	// take the same exclusive lock a Prune sweep takes, so the two never
+	// interleave reads and writes of repository.json, and reload under the
+	// lock so this call acts on the latest state rather than whatever was in
+	// memory when the registry was opened
+	release, err := r.acquireLock(lockTimeout)
+	core.CheckErr(err, "failed to acquire local registry lock")
+	defer release()
+	r.load()
 	// gets the full base name (with extension)
 	basename := filepath.Base(filename)
 	// gets the basename directory only
@@ -196,10 +254,24 @@ func (r *FileRegistry) Add(filename string, name *core.ArtieName, s *core.Seal)
 	if basenameExt != ".zip" {
 		log.Fatal(errors.New(fmt.Sprintf("the localRepo can only accept zip files, the extension provided was %s", basenameExt)))
 	}
-	// move the zip file to the localRepo folder
-	core.CheckErr(RenameFile(filename, filepath.Join(r.Path(), basename), false), "failed to move artefact zip file to the local registry")
-	// now move the seal file to the localRepo folder
-	core.CheckErr(RenameFile(filepath.Join(basenameDir, fmt.Sprintf("%s.json", basenameNoExt)), filepath.Join(r.Path(), fmt.Sprintf("%s.json", basenameNoExt)), false), "failed to move artefact seal file to the local registry")
+	sealFilename := filepath.Join(basenameDir, fmt.Sprintf("%s.json", basenameNoExt))
+	// compute a per-entry digest list for the zip, so tampering or bit-rot
+	// can later be pinpointed to the offending file rather than only
+	// detected at the whole-archive level, and record it in the seal
+	entries, err := core.ZipEntryDigests(filename)
+	core.CheckErr(err, "failed to compute artefact entry digests")
+	s.Manifest.Entries = entries
+	core.CheckErr(ioutil.WriteFile(sealFilename, core.ToJsonBytes(s), os.ModePerm), "failed to update seal with entry digests")
+	// the artefact Id is the sha256 digest of the zip blob itself, not a
+	// composite of its entries: it is served verbatim as the OCI layer
+	// digest (see ociIndex.manifestBytes), and any OCI client downloading
+	// it checks the bytes it received hash to this same value, so it must
+	// always be a real digest of the blob's content
+	digest, err := fileDigest(filename)
+	core.CheckErr(err, "failed to compute artefact digest")
+	// put the zip and seal blobs in the configured blob storage backend
+	core.CheckErr(r.putBlob(filename, r.zipKey(basenameNoExt)), "failed to store artefact zip file")
+	core.CheckErr(r.putBlob(sealFilename, r.sealKey(basenameNoExt)), "failed to store artefact seal file")
 	// untag artefact artefact (if any)
 	r.unTag(name, name.Tag)
 	// find the repository
@@ -207,14 +279,14 @@ func (r *FileRegistry) Add(filename string, name *core.ArtieName, s *core.Seal)
 	// if the repo does not exist the creates it
 	if repo == nil {
 		repo = &repository{
-			Repository: name.FullyQualifiedName(),
+			Repository: canonicalRepositoryName(name.FullyQualifiedName()),
 			Artefacts:  make([]*artefact, 0),
 		}
 		r.Repositories = append(r.Repositories, repo)
 	}
 	// creates a new artefact
 	artefacts := append(repo.Artefacts, &artefact{
-		Id:      core.ArtefactId(s),
+		Id:      digest,
 		Type:    s.Manifest.Type,
 		FileRef: basenameNoExt,
 		Tags:    []string{name.Tag},
@@ -247,9 +319,10 @@ func (r *FileRegistry) removeArtefactById(a []*artefact, id string) []*artefact
 
 func (r *FileRegistry) removeRepoByName(a []*repository, name *core.ArtieName) []*repository {
 	i := -1
+	canonical := canonicalRepositoryName(name.FullyQualifiedName())
 	// find an artefact with the specified tag
 	for ix := 0; ix < len(a); ix++ {
-		if a[ix].Repository == name.FullyQualifiedName() {
+		if canonicalRepositoryName(a[ix].Repository) == canonical {
 			i = ix
 			break
 		}
@@ -275,6 +348,12 @@ func (r *FileRegistry) unTag(name *core.ArtieName, tag string) {
 
 // remove a given tag from an artefact
 func (r *FileRegistry) Tag(sourceName *core.ArtieName, targetName *core.ArtieName) {
+	// take the same exclusive lock a Prune sweep takes, so the two never
+	// interleave reads and writes of repository.json
+	release, err := r.acquireLock(lockTimeout)
+	core.CheckErr(err, "failed to acquire local registry lock")
+	defer release()
+	r.load()
 	sourceArtie := r.GetArtefact(sourceName)
 	if sourceArtie == nil {
 		core.RaiseErr("source artefact %s does not exit", sourceName)
@@ -297,7 +376,7 @@ func (r *FileRegistry) Tag(sourceName *core.ArtieName, targetName *core.ArtieNam
 			core.Msg("tagging %s", sourceName)
 			newArtie.Tags = []string{targetName.Tag}
 			r.Repositories = append(r.Repositories, &repository{
-				Repository: targetName.FullyQualifiedName(),
+				Repository: canonicalRepositoryName(targetName.FullyQualifiedName()),
 				Artefacts: []*artefact{
 					&artefact{
 						Id:      sourceArtie.Id,
@@ -366,37 +445,46 @@ func (r *FileRegistry) unTagAll(name *core.ArtieName) {
 	r.save()
 }
 
-// List artefacts to stdout
-func (r *FileRegistry) List() {
+// List artefacts to stdout. When verify is true, each artefact's zip blob is
+// checked against its seal's recorded entry digests and an extra VERIFIED
+// column reports the outcome.
+func (r *FileRegistry) List(verify bool) {
 	// get a table writer for the stdout
 	w := tabwriter.NewWriter(os.Stdout, 0, 0, 12, ' ', 0)
 	// print the header row
-	_, err := fmt.Fprintln(w, "REPOSITORY\tTAG\tARTEFACT ID\tARTEFACT TYPE\tCREATED\tSIZE")
+	header := "REPOSITORY\tTAG\tARTEFACT ID\tARTEFACT TYPE\tCREATED\tSIZE"
+	if verify {
+		header += "\tVERIFIED"
+	}
+	_, err := fmt.Fprintln(w, header)
 	core.CheckErr(err, "failed to write table header")
 	// repository, tag, artefact id, created, size
 	for _, repo := range r.Repositories {
+		// print the short, familiar form of the repository name so it
+		// round-trips with what a user would type on the command line
+		name := repo.Repository
+		if ref, err := reference.Parse(repo.Repository); err == nil {
+			name = ref.Familiar()
+		}
 		for _, a := range repo.Artefacts {
+			status := ""
+			if verify {
+				status = "\t" + r.verifyStatus(a)
+			}
+			tags := a.Tags
 			// if the artefact is dangling (no tags)
-			if len(a.Tags) == 0 {
-				_, err := fmt.Fprintln(w, fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s",
-					repo.Repository,
-					"<none>",
-					a.Id[7:19],
-					a.Type,
-					toElapsedLabel(a.Created),
-					a.Size),
-				)
-				core.CheckErr(err, "failed to write output")
+			if len(tags) == 0 {
+				tags = []string{"<none>"}
 			}
-			for _, tag := range a.Tags {
-				_, err := fmt.Fprintln(w, fmt.Sprintf("%s\t%s\t%s\t%s\t%s\t%s",
-					repo.Repository,
+			for _, tag := range tags {
+				_, err := fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s%s\n",
+					name,
 					tag,
 					a.Id[7:19],
 					a.Type,
 					toElapsedLabel(a.Created),
-					a.Size),
-				)
+					a.Size,
+					status)
 				core.CheckErr(err, "failed to write output")
 			}
 		}
@@ -420,22 +508,35 @@ func (r *FileRegistry) ListQ() {
 	core.CheckErr(err, "failed to flush output")
 }
 
-func (r *FileRegistry) Push(name *core.ArtieName, remote Remote, credentials string) {
+// Push does not take the registry lock: it only reads repository.json and
+// never calls save, so it has nothing for the lock to protect against a
+// concurrent Add, Tag, Remove or Prune.
+func (r *FileRegistry) Push(name *core.ArtieName, remote Remote, credentials string, opts TransferOptions) {
+	opts = opts.withDefaults()
 	// fetch the artefact info from the local registry
 	artie := r.GetArtefact(name)
 	if artie == nil {
 		log.Fatal(errors.New(fmt.Sprintf("artefact %s not found in the local registry", name)))
 	}
-	// set up an http client
+	// set up an http client, only skipping certificate verification if
+	// explicitly asked to
 	client := &http.Client{
 		Transport: &http.Transport{
 			TLSClientConfig: &tls.Config{
-				InsecureSkipVerify: true,
+				InsecureSkipVerify: opts.Insecure,
 			},
 		},
 	}
+	// stream the zip and seal blobs straight from the configured backend, so
+	// large artefacts never need to be pre-staged on the CLI host
+	zip, _, err := r.openBlob(r.zipKey(artie.FileRef))
+	core.CheckErr(err, "failed to open artefact zip blob")
+	defer zip.Close()
+	seal, _, err := r.openBlob(r.sealKey(artie.FileRef))
+	core.CheckErr(err, "failed to open artefact seal blob")
+	defer seal.Close()
 	// execute the upload
-	err := remote.UploadArtefact(client, name, r.Path(), artie.FileRef, credentials)
+	err = remote.UploadArtefact(client, name, zip, seal, credentials, opts)
 	if err != nil {
 		log.Fatal(err)
 	}
@@ -443,6 +544,12 @@ func (r *FileRegistry) Push(name *core.ArtieName, remote Remote, credentials str
 }
 
 func (r *FileRegistry) Remove(names []*core.ArtieName) {
+	// take the same exclusive lock a Prune sweep takes, so the two never
+	// interleave reads and writes of repository.json
+	release, err := r.acquireLock(lockTimeout)
+	core.CheckErr(err, "failed to acquire local registry lock")
+	defer release()
+	r.load()
 	for _, name := range names {
 		// try and get the artefact by complete URI or id ref
 		artie := r.GetArtefact(name)
@@ -491,19 +598,196 @@ func (r *FileRegistry) Remove(names []*core.ArtieName) {
 
 // remove the files associated with an artefact
 func (r *FileRegistry) removeFiles(artie *artefact) {
-	// remove the zip file
-	err := os.Remove(fmt.Sprintf("%s/%s.zip", r.Path(), artie.FileRef))
+	ctx := context.Background()
+	// remove the zip blob
+	core.CheckErr(r.store.Delete(ctx, r.zipKey(artie.FileRef)), "failed to remove artefact zip blob")
+	// remove the seal blob
+	core.CheckErr(r.store.Delete(ctx, r.sealKey(artie.FileRef)), "failed to remove artefact seal blob")
+}
+
+func (r *FileRegistry) Pull(name *core.ArtieName, remote Remote, credentials string, opts TransferOptions) {
+	opts = opts.withDefaults()
+	// set up an http client, only skipping certificate verification if
+	// explicitly asked to
+	client := &http.Client{
+		Transport: &http.Transport{
+			TLSClientConfig: &tls.Config{
+				InsecureSkipVerify: opts.Insecure,
+			},
+		},
+	}
+	// download the manifest and its blobs to a temporary staging area; the
+	// zip blob is fetched in resumable chunks, continuing from any .part
+	// file left behind by a previous interrupted pull
+	stagingDir := filepath.Join(r.Path(), ".pulls", name.Name)
+	core.CheckErr(os.MkdirAll(stagingDir, 0755), "failed to create pull staging directory")
+	defer os.RemoveAll(stagingDir)
+	fileRef, err := remote.DownloadArtefact(client, name, stagingDir, credentials, opts)
 	if err != nil {
 		log.Fatal(err)
 	}
-	// remove the json file
-	err = os.Remove(fmt.Sprintf("%s/%s.json", r.Path(), artie.FileRef))
+	seal, err := core.NewSealFromFile(filepath.Join(stagingDir, fmt.Sprintf("%s.json", fileRef)))
+	core.CheckErr(err, "failed to read downloaded seal")
+	// recompute the zip's per-entry digests and check them against the seal
+	// before the artefact is accepted into the local registry, so a
+	// corrupted or tampered download is caught at the file level rather
+	// than only detected by a whole-blob digest mismatch
+	zipFile := filepath.Join(stagingDir, fmt.Sprintf("%s.zip", fileRef))
+	core.CheckErr(verifyEntries(zipFile, seal), "artefact failed tamper verification")
+	// register the downloaded artefact in the local registry
+	r.Add(zipFile, name, seal)
+	fmt.Printf("pulled %s\n", name.String())
+}
+
+// VerifyResult reports the outcome of FileRegistry.Verify for a single
+// artefact.
+type VerifyResult struct {
+	// Name is the artefact name or id that was verified.
+	Name string
+	// Ok is true when every recorded entry digest matched.
+	Ok bool
+	// Mismatched lists entries whose digest no longer matches the seal.
+	Mismatched []string
+	// Missing lists entries recorded in the seal but absent from the zip.
+	Missing []string
+	// Extra lists entries present in the zip but not recorded in the seal.
+	Extra []string
+}
+
+// Verify recomputes the per-entry digest list of the artefact identified by
+// name against its stored zip blob and compares it with the digests
+// recorded in its seal, detecting tampering or bit-rot at the file level
+// rather than only at the whole-zip level. It backs the "artie verify"
+// command.
+func (r *FileRegistry) Verify(name *core.ArtieName) (*VerifyResult, error) {
+	artie := r.GetArtefact(name)
+	if artie == nil {
+		return nil, fmt.Errorf("artefact %s not found in the local registry", name)
+	}
+	result, err := r.verifyArtefact(artie)
 	if err != nil {
-		log.Fatal(err)
+		return nil, err
 	}
+	result.Name = name.String()
+	return result, nil
 }
 
-func (r *FileRegistry) Pull(name *core.ArtieName, remote Remote) {
+// verifyArtefact stages artie's zip blob to a temporary file, recomputes its
+// per-entry digests and diffs them against the entries recorded in its
+// seal.
+func (r *FileRegistry) verifyArtefact(artie *artefact) (*VerifyResult, error) {
+	zipRC, _, err := r.openBlob(r.zipKey(artie.FileRef))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artefact zip blob: %w", err)
+	}
+	defer zipRC.Close()
+	// archive/zip needs random access, so stage the blob to a local file
+	// regardless of which backend it actually lives in
+	tmp, err := ioutil.TempFile("", "artie-verify-*.zip")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err := io.Copy(tmp, zipRC); err != nil {
+		return nil, fmt.Errorf("failed to stage artefact zip blob: %w", err)
+	}
+	sealRC, _, err := r.openBlob(r.sealKey(artie.FileRef))
+	if err != nil {
+		return nil, fmt.Errorf("failed to open artefact seal blob: %w", err)
+	}
+	defer sealRC.Close()
+	sealBytes, err := ioutil.ReadAll(sealRC)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read artefact seal blob: %w", err)
+	}
+	var s core.Seal
+	if err := json.Unmarshal(sealBytes, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse artefact seal: %w", err)
+	}
+	actual, err := core.ZipEntryDigests(tmp.Name())
+	if err != nil {
+		return nil, fmt.Errorf("failed to digest artefact zip blob: %w", err)
+	}
+	result := &VerifyResult{Name: artie.Id}
+	recorded := make(map[string]string, len(s.Manifest.Entries))
+	for _, e := range s.Manifest.Entries {
+		recorded[e.Name] = e.Digest
+	}
+	seen := make(map[string]bool, len(actual))
+	for _, e := range actual {
+		seen[e.Name] = true
+		want, ok := recorded[e.Name]
+		if !ok {
+			result.Extra = append(result.Extra, e.Name)
+			continue
+		}
+		if want != e.Digest {
+			result.Mismatched = append(result.Mismatched, e.Name)
+		}
+	}
+	for entryName := range recorded {
+		if !seen[entryName] {
+			result.Missing = append(result.Missing, entryName)
+		}
+	}
+	result.Ok = len(result.Mismatched) == 0 && len(result.Missing) == 0 && len(result.Extra) == 0
+	return result, nil
+}
+
+// verifyStatus summarises verifyArtefact's outcome for the List table.
+func (r *FileRegistry) verifyStatus(artie *artefact) string {
+	result, err := r.verifyArtefact(artie)
+	if err != nil {
+		return fmt.Sprintf("ERROR: %s", err)
+	}
+	if result.Ok {
+		return "OK"
+	}
+	return fmt.Sprintf("FAILED (%d mismatched, %d missing, %d extra)",
+		len(result.Mismatched), len(result.Missing), len(result.Extra))
+}
+
+// verifyEntries recomputes the per-entry digest list of the zip at zipPath
+// and checks it against the digests recorded in seal, failing fast on the
+// first entry that does not match.
+func verifyEntries(zipPath string, seal *core.Seal) error {
+	actual, err := core.ZipEntryDigests(zipPath)
+	if err != nil {
+		return fmt.Errorf("failed to digest artefact zip file: %w", err)
+	}
+	recorded := make(map[string]string, len(seal.Manifest.Entries))
+	for _, e := range seal.Manifest.Entries {
+		recorded[e.Name] = e.Digest
+	}
+	if len(actual) != len(recorded) {
+		return fmt.Errorf("artefact zip file has a different number of entries than its seal")
+	}
+	for _, e := range actual {
+		want, ok := recorded[e.Name]
+		if !ok {
+			return fmt.Errorf("entry %s is not present in the artefact seal", e.Name)
+		}
+		if want != e.Digest {
+			return fmt.Errorf("entry %s failed digest verification, the artefact may have been tampered with or corrupted", e.Name)
+		}
+	}
+	return nil
+}
+
+// fileDigest returns the sha256 digest of the content of filename in
+// "sha256:<hex>" form.
+func fileDigest(filename string) (string, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return "sha256:" + hex.EncodeToString(h.Sum(nil)), nil
 }
 
 // returns the elapsed time until now in human friendly format
@@ -545,12 +829,44 @@ func plural(value int64, label string) string {
 	return label
 }
 
-// the fully qualified name of the json Seal file in the local localReg
-func (r *FileRegistry) regDirJsonFilename(uniqueIdName string) string {
-	return fmt.Sprintf("%s/%s.json", r.Path(), uniqueIdName)
+// the blob storage key of the seal JSON for the artefact identified by fileRef
+func (r *FileRegistry) sealKey(fileRef string) string {
+	return fmt.Sprintf("%s.json", fileRef)
+}
+
+// the blob storage key of the zip file for the artefact identified by fileRef
+func (r *FileRegistry) zipKey(fileRef string) string {
+	return fmt.Sprintf("%s.zip", fileRef)
+}
+
+// putBlob adopts the local file at srcPath into the configured blob storage
+// backend under key, renaming it directly when the backend is local disk to
+// avoid a redundant copy, and streaming it otherwise.
+func (r *FileRegistry) putBlob(srcPath, key string) error {
+	if renamer, ok := r.store.(blob.Renamer); ok {
+		return renamer.Rename(srcPath, key)
+	}
+	info, err := os.Stat(srcPath)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := r.store.Put(context.Background(), key, f, info.Size()); err != nil {
+		return err
+	}
+	return os.Remove(srcPath)
+}
+
+// openBlob opens the blob stored under key for reading.
+func (r *FileRegistry) openBlob(key string) (io.ReadCloser, int64, error) {
+	return r.store.Get(context.Background(), key)
 }
 
-// the fully qualified name of the zip file in the local localReg
-func (r *FileRegistry) regDirZipFilename(uniqueIdName string) string {
-	return fmt.Sprintf("%s/%s.zip", r.Path(), uniqueIdName)
+// statBlob returns the size in bytes of the blob stored under key.
+func (r *FileRegistry) statBlob(key string) (int64, error) {
+	return r.store.Stat(context.Background(), key)
 }
\ No newline at end of file
@@ -0,0 +1,306 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package registry
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/gatblau/onix/artie/core"
+)
+
+// RetentionPolicy controls which artefacts GC.Prune is allowed to remove.
+type RetentionPolicy struct {
+	// KeepLastN retains the N most recently created tagged revisions in each
+	// repository regardless of age or KeepYoungerThan. Zero means no limit.
+	KeepLastN int
+	// KeepYoungerThan retains any tagged revision or dangling artefact
+	// created within this duration of now. Zero means no age exemption.
+	KeepYoungerThan time.Duration
+	// KeepTagPatterns is a list of glob patterns, as accepted by path.Match,
+	// matched against tags; a tag matching any pattern is never removed.
+	KeepTagPatterns []string
+	// DryRun reports what would be removed without changing anything.
+	DryRun bool
+	// Force allows Prune to run without any of KeepLastN, KeepYoungerThan or
+	// KeepTagPatterns set, a policy that would otherwise keep nothing at all
+	// and wipe every tagged revision and dangling artefact in the registry.
+	Force bool
+}
+
+// hasRetentionCriteria reports whether policy keeps anything at all, i.e.
+// whether at least one of KeepLastN, KeepYoungerThan or KeepTagPatterns is
+// set. A policy with none of these set would untag and then delete every
+// artefact in the registry, so Prune refuses to run one unless Force is set.
+func (p RetentionPolicy) hasRetentionCriteria() bool {
+	return p.KeepLastN > 0 || p.KeepYoungerThan > 0 || len(p.KeepTagPatterns) > 0
+}
+
+// PruneSummary reports the outcome of a GC.Prune sweep.
+type PruneSummary struct {
+	// TagsRemoved is the number of tagged revisions untagged for falling
+	// outside the retention policy.
+	TagsRemoved int
+	// ArtefactsRemoved is the number of dangling artefacts deleted.
+	ArtefactsRemoved int
+	// OrphansRemoved is the number of on-disk blobs deleted because no
+	// artefact in repository.json referenced their FileRef.
+	OrphansRemoved int
+	// BytesFreed is the total size of the blobs actually deleted.
+	BytesFreed int64
+	// DryRun echoes the policy that produced this summary.
+	DryRun bool
+}
+
+// GC performs retention-policy-driven garbage collection over a FileRegistry.
+type GC struct {
+	reg *FileRegistry
+}
+
+// NewGC creates a GC bound to r.
+func NewGC(r *FileRegistry) *GC {
+	return &GC{reg: r}
+}
+
+// lockTimeout bounds how long Prune, Add, Tag and Remove wait to acquire the
+// exclusive lock on repository.json before giving up, so one of them holding
+// the lock cannot stall another indefinitely. Push never takes the lock, and
+// Pull only holds it while its final Add runs.
+const lockTimeout = 30 * time.Second
+
+const lockPollInterval = 100 * time.Millisecond
+
+// Prune enumerates every artefact on disk and in repository.json and
+// removes (1) tagged revisions that fall outside policy, (2) artefacts left
+// dangling as a result (or already dangling) outside the retention window,
+// and (3) orphan blobs whose FileRef is referenced by nothing at all. It
+// backs the "artie prune" command.
+//
+// The sweep runs under an exclusive, bounded-timeout lock on repository.json,
+// the same lock FileRegistry.Add, Tag and Remove take around their own
+// load-mutate-save sequence, so a Prune cannot race a concurrent mutation; in
+// DryRun mode nothing is locked, deleted or saved, and the returned summary
+// only reports what would happen.
+func (g *GC) Prune(policy RetentionPolicy) (*PruneSummary, error) {
+	if !policy.DryRun && !policy.Force && !policy.hasRetentionCriteria() {
+		return nil, fmt.Errorf("refusing to prune: policy keeps nothing, since none of KeepLastN, KeepYoungerThan or KeepTagPatterns is set; pass at least one, use DryRun to preview, or set Force to proceed anyway")
+	}
+	summary := &PruneSummary{DryRun: policy.DryRun}
+	if !policy.DryRun {
+		release, err := g.reg.acquireLock(lockTimeout)
+		if err != nil {
+			return nil, err
+		}
+		defer release()
+	}
+	// reload the registry metadata under the lock, so the sweep acts on the
+	// latest state rather than whatever was in memory when GC started
+	g.reg.load()
+
+	now := time.Now()
+
+	if err := g.pruneTags(policy, now, summary); err != nil {
+		return nil, err
+	}
+	if err := g.pruneDangling(policy, now, summary); err != nil {
+		return nil, err
+	}
+	if err := g.pruneOrphans(policy, summary); err != nil {
+		return nil, err
+	}
+
+	if !policy.DryRun {
+		g.reg.save()
+	}
+	return summary, nil
+}
+
+// pruneTags untags revisions older than the retention policy in each
+// repository, most recently created first, so KeepLastN keeps the newest N.
+func (g *GC) pruneTags(policy RetentionPolicy, now time.Time, summary *PruneSummary) error {
+	type revision struct {
+		tag      string
+		artefact *artefact
+		created  time.Time
+	}
+	for _, repo := range g.reg.Repositories {
+		var revisions []revision
+		for _, a := range repo.Artefacts {
+			created, err := time.Parse(time.RFC850, a.Created)
+			if err != nil {
+				return fmt.Errorf("failed to parse created time for artefact %s: %w", a.Id, err)
+			}
+			for _, tag := range a.Tags {
+				revisions = append(revisions, revision{tag: tag, artefact: a, created: created})
+			}
+		}
+		sort.Slice(revisions, func(i, j int) bool {
+			return revisions[i].created.After(revisions[j].created)
+		})
+		kept := 0
+		for _, rev := range revisions {
+			if matchesAnyPattern(rev.tag, policy.KeepTagPatterns) {
+				continue
+			}
+			if policy.KeepYoungerThan > 0 && now.Sub(rev.created) < policy.KeepYoungerThan {
+				continue
+			}
+			if policy.KeepLastN > 0 && kept < policy.KeepLastN {
+				kept++
+				continue
+			}
+			core.Msg("pruning tag %s:%s%s", repo.Repository, rev.tag, dryRunSuffix(policy.DryRun))
+			// the tag is removed in memory even in DryRun mode, so pruneDangling
+			// sees the artefacts a real sweep would leave dangling; a dry run
+			// never saves this change
+			rev.artefact.Tags = core.RemoveElement(rev.artefact.Tags, rev.tag)
+			summary.TagsRemoved++
+		}
+	}
+	return nil
+}
+
+// pruneDangling removes artefacts with no tags left once they fall outside
+// the retention window.
+func (g *GC) pruneDangling(policy RetentionPolicy, now time.Time, summary *PruneSummary) error {
+	for _, repo := range g.reg.Repositories {
+		remaining := make([]*artefact, 0, len(repo.Artefacts))
+		for _, a := range repo.Artefacts {
+			if len(a.Tags) > 0 {
+				remaining = append(remaining, a)
+				continue
+			}
+			created, err := time.Parse(time.RFC850, a.Created)
+			if err != nil {
+				return fmt.Errorf("failed to parse created time for artefact %s: %w", a.Id, err)
+			}
+			if policy.KeepYoungerThan > 0 && now.Sub(created) < policy.KeepYoungerThan {
+				remaining = append(remaining, a)
+				continue
+			}
+			core.Msg("pruning dangling artefact %s%s", a.Id, dryRunSuffix(policy.DryRun))
+			if !policy.DryRun {
+				freed, err := g.reg.removeFilesAndSize(a)
+				summary.BytesFreed += freed
+				if err != nil {
+					return err
+				}
+			} else {
+				// its blobs are not deleted in DryRun mode, so keep it referenced
+				// and pruneOrphans does not count them a second time as orphans
+				remaining = append(remaining, a)
+			}
+			summary.ArtefactsRemoved++
+		}
+		repo.Artefacts = remaining
+	}
+	return nil
+}
+
+// pruneOrphans deletes on-disk blobs whose FileRef is not referenced by any
+// remaining artefact in repository.json.
+func (g *GC) pruneOrphans(policy RetentionPolicy, summary *PruneSummary) error {
+	referenced := make(map[string]bool)
+	for _, repo := range g.reg.Repositories {
+		for _, a := range repo.Artefacts {
+			referenced[a.FileRef] = true
+		}
+	}
+	ctx := context.Background()
+	keys, err := g.reg.store.List(ctx, "")
+	if err != nil {
+		return fmt.Errorf("failed to list blob storage: %w", err)
+	}
+	for _, key := range keys {
+		fileRef := strings.TrimSuffix(strings.TrimSuffix(key, ".zip"), ".json")
+		if referenced[fileRef] {
+			continue
+		}
+		core.Msg("pruning orphan blob %s%s", key, dryRunSuffix(policy.DryRun))
+		if !policy.DryRun {
+			if size, err := g.reg.store.Stat(ctx, key); err == nil {
+				summary.BytesFreed += size
+			}
+			if err := g.reg.store.Delete(ctx, key); err != nil {
+				return fmt.Errorf("failed to delete orphan blob %s: %w", key, err)
+			}
+		}
+		summary.OrphansRemoved++
+	}
+	return nil
+}
+
+// matchesAnyPattern reports whether tag matches any of patterns.
+func matchesAnyPattern(tag string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if ok, err := path.Match(pattern, tag); err == nil && ok {
+			return true
+		}
+	}
+	return false
+}
+
+// dryRunSuffix annotates a GC log message when dryRun is true.
+func dryRunSuffix(dryRun bool) string {
+	if dryRun {
+		return " (dry-run)"
+	}
+	return ""
+}
+
+// lockFile is the path of the advisory lock taken over repository.json
+// while a GC sweep, Add, Tag or Remove is in progress.
+func (r *FileRegistry) lockFile() string {
+	return filepath.Join(r.Path(), "repository.json.lock")
+}
+
+// acquireLock takes an exclusive, advisory lock on repository.json,
+// retrying every lockPollInterval until timeout elapses. The returned
+// function releases the lock and must always be called.
+func (r *FileRegistry) acquireLock(timeout time.Duration) (func(), error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		f, err := os.OpenFile(r.lockFile(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0644)
+		if err == nil {
+			f.Close()
+			return func() {
+				os.Remove(r.lockFile())
+			}, nil
+		}
+		if !os.IsExist(err) {
+			return nil, err
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out after %s waiting for the lock on %s", timeout, r.file())
+		}
+		time.Sleep(lockPollInterval)
+	}
+}
+
+// removeFilesAndSize removes the zip and seal blobs for artie, returning the
+// total bytes freed. It returns whatever was freed even when an error is
+// returned, so callers can still account for partial progress.
+func (r *FileRegistry) removeFilesAndSize(artie *artefact) (int64, error) {
+	ctx := context.Background()
+	var freed int64
+	for _, key := range []string{r.zipKey(artie.FileRef), r.sealKey(artie.FileRef)} {
+		if size, err := r.store.Stat(ctx, key); err == nil {
+			freed += size
+		}
+		if err := r.store.Delete(ctx, key); err != nil {
+			return freed, err
+		}
+	}
+	return freed, nil
+}
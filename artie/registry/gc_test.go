@@ -0,0 +1,125 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package registry
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// TestPruneDoesNotRemoveRegistryFiles populates a registry directory shaped
+// like a real default registry (repository.json alongside config.yaml and
+// auth.json, with blobs in their own sub-directory) and asserts that running
+// Prune never touches the registry's own metadata or credential files, and
+// leaves referenced blobs alone.
+func TestPruneDoesNotRemoveRegistryFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artie-registry-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r, err := newFileRegistryAt(dir)
+	if err != nil {
+		t.Fatalf("failed to create test registry: %v", err)
+	}
+
+	// files that live alongside repository.json in a real registry directory
+	for _, name := range []string{"config.yaml", "auth.json"} {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte("{}"), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// a referenced artefact, added directly (bypassing Add/core.ArtieName)
+	const fileRef = "kept-artefact"
+	if err := r.store.Put(context.Background(), r.zipKey(fileRef), strings.NewReader("zip content"), 11); err != nil {
+		t.Fatal(err)
+	}
+	if err := r.store.Put(context.Background(), r.sealKey(fileRef), strings.NewReader("{}"), 2); err != nil {
+		t.Fatal(err)
+	}
+	r.Repositories = append(r.Repositories, &repository{
+		Repository: "lib/kept",
+		Artefacts: []*artefact{
+			{
+				Id:      "sha256:deadbeef",
+				FileRef: fileRef,
+				Tags:    []string{"v1"},
+				Created: time.Now().Format(time.RFC850),
+			},
+		},
+	})
+	r.save()
+
+	// a real-world policy that keeps anything created in the last hour, so
+	// the freshly added artefact above is retained and this test exercises
+	// pruneOrphans without also exercising the "keep nothing" policy tested
+	// separately below
+	if _, err := NewGC(r).Prune(RetentionPolicy{KeepYoungerThan: time.Hour}); err != nil {
+		t.Fatalf("Prune failed: %v", err)
+	}
+
+	for _, name := range []string{"repository.json", "config.yaml", "auth.json"} {
+		if _, err := os.Stat(filepath.Join(dir, name)); err != nil {
+			t.Errorf("expected %s to survive Prune, got: %v", name, err)
+		}
+	}
+	if _, _, err := r.openBlob(r.zipKey(fileRef)); err != nil {
+		t.Errorf("expected referenced blob %s to survive Prune, got: %v", fileRef, err)
+	}
+}
+
+// TestPruneEmptyPolicyRequiresForce ensures the zero value of RetentionPolicy
+// -- which is what "artie prune" runs with no flags -- is refused rather than
+// silently untagging and deleting every artefact in the registry, since none
+// of KeepLastN, KeepYoungerThan or KeepTagPatterns would keep anything.
+func TestPruneEmptyPolicyRequiresForce(t *testing.T) {
+	dir, err := ioutil.TempDir("", "artie-registry-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	r, err := newFileRegistryAt(dir)
+	if err != nil {
+		t.Fatalf("failed to create test registry: %v", err)
+	}
+	r.Repositories = append(r.Repositories, &repository{
+		Repository: "lib/kept",
+		Artefacts: []*artefact{
+			{
+				Id:      "sha256:deadbeef",
+				FileRef: "kept-artefact",
+				Tags:    []string{"v1"},
+				Created: time.Now().Format(time.RFC850),
+			},
+		},
+	})
+	r.save()
+
+	if _, err := NewGC(r).Prune(RetentionPolicy{}); err == nil {
+		t.Fatal("expected Prune to refuse an empty, non-forced retention policy")
+	}
+	if len(r.Repositories) != 1 || len(r.Repositories[0].Artefacts[0].Tags) != 1 {
+		t.Fatalf("expected the refused Prune to leave the registry untouched, got: %+v", r.Repositories)
+	}
+
+	summary, err := NewGC(r).Prune(RetentionPolicy{Force: true})
+	if err != nil {
+		t.Fatalf("expected a forced empty policy to be allowed to run, got: %v", err)
+	}
+	if summary.TagsRemoved != 1 || summary.ArtefactsRemoved != 1 {
+		t.Errorf("expected Force to prune the only tag and the now-dangling artefact, got: %+v", summary)
+	}
+}
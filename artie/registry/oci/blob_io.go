@@ -0,0 +1,22 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package oci
+
+import "os"
+
+// openStagedBlob opens a blob staged on disk by an upload session for handing
+// off to the Index.
+func openStagedBlob(path string) (*os.File, error) {
+	return os.Open(path)
+}
+
+// removeStagedBlob discards the staging file once its content has been handed
+// off to the Index.
+func removeStagedBlob(path string) {
+	os.Remove(path)
+}
@@ -0,0 +1,41 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package oci
+
+import "io"
+
+// Index is implemented by a registry backend so that Server can serve the OCI
+// Distribution Spec v2 API without knowing how artefacts are actually stored.
+// registry.FileRegistry provides the concrete implementation used in production.
+type Index interface {
+	// Repositories returns the name of every repository known to the index.
+	Repositories() []string
+
+	// Manifest returns the manifest bytes and descriptor for the given reference
+	// (tag or digest) within repo. ok is false if the reference cannot be resolved.
+	Manifest(repo, ref string) (body []byte, desc Descriptor, ok bool)
+
+	// PutManifest stores body as the manifest for ref within repo and returns the
+	// digest assigned to it.
+	PutManifest(repo, ref string, body []byte) (digest string, err error)
+
+	// DeleteManifest removes the reference from repo. It does not necessarily
+	// remove the underlying blobs, which remain until garbage collected.
+	DeleteManifest(repo, ref string) error
+
+	// BlobExists reports whether digest is present in repo, along with its size.
+	BlobExists(repo, digest string) (size int64, ok bool)
+
+	// OpenBlob returns a reader positioned at the start of the blob identified by
+	// digest within repo, along with its total size.
+	OpenBlob(repo, digest string) (r io.ReadCloser, size int64, err error)
+
+	// PutBlob stores the content read from r as digest within repo, verifying
+	// that it hashes to digest and is exactly size bytes long.
+	PutBlob(repo, digest string, size int64, r io.Reader) error
+}
@@ -0,0 +1,268 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package oci
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Server implements the OCI Distribution Spec v2 HTTP API on top of an Index,
+// so that any OCI-compliant client (crane, skopeo, Harbor, docker, etc.) can
+// push and pull Artie artefacts as if they were container images.
+type Server struct {
+	Index   Index
+	uploads *uploads
+}
+
+// NewServer creates an OCI v2 API server backed by index. tmpDir is used to
+// stage blob uploads while they are in progress.
+func NewServer(index Index, tmpDir string) *Server {
+	return &Server{Index: index, uploads: newUploads(tmpDir)}
+}
+
+// ServeHTTP dispatches a request to the appropriate v2 API handler based on its
+// path, following the routes defined by the distribution spec.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, "/v2")
+	if path == "" || path == "/" {
+		s.handleBase(w, r)
+		return
+	}
+	path = strings.TrimPrefix(path, "/")
+
+	if i := strings.Index(path, "/blobs/uploads/"); i >= 0 {
+		s.handleUploads(w, r, path[:i], path[i+len("/blobs/uploads/"):])
+		return
+	}
+	if i := strings.Index(path, "/blobs/"); i >= 0 {
+		s.handleBlob(w, r, path[:i], path[i+len("/blobs/"):])
+		return
+	}
+	if i := strings.Index(path, "/manifests/"); i >= 0 {
+		s.handleManifest(w, r, path[:i], path[i+len("/manifests/"):])
+		return
+	}
+	if strings.HasSuffix(path, "/tags/list") {
+		s.handleTagsList(w, r, strings.TrimSuffix(path, "/tags/list"))
+		return
+	}
+	s.writeError(w, http.StatusNotFound, "NAME_UNKNOWN", "unrecognised v2 route", path)
+}
+
+// handleBase implements GET /v2/, used by clients to probe API support.
+func (s *Server) handleBase(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		s.writeError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "method not allowed", r.Method)
+		return
+	}
+	w.Header().Set("Docker-Distribution-Api-Version", "registry/2.0")
+	w.WriteHeader(http.StatusOK)
+}
+
+// handleTagsList implements GET /v2/<name>/tags/list.
+func (s *Server) handleTagsList(w http.ResponseWriter, r *http.Request, repo string) {
+	repo = strings.TrimSuffix(repo, "/")
+	tags := make([]string, 0)
+	for _, name := range s.Index.Repositories() {
+		if name == repo {
+			tags = s.tagsFor(repo)
+			break
+		}
+	}
+	s.writeJSON(w, http.StatusOK, TagList{Name: repo, Tags: tags})
+}
+
+// tagsFor returns the tags of repo when the Index also implements a
+// Tags(repo) method, as the FileRegistry backed index does, since repository
+// layout is backend specific; for any other Index it returns an empty list.
+func (s *Server) tagsFor(repo string) []string {
+	type tagger interface {
+		Tags(repo string) []string
+	}
+	if t, ok := s.Index.(tagger); ok {
+		return t.Tags(repo)
+	}
+	return []string{}
+}
+
+// handleManifest implements HEAD/GET/PUT/DELETE /v2/<name>/manifests/<ref>.
+func (s *Server) handleManifest(w http.ResponseWriter, r *http.Request, repo, ref string) {
+	repo = strings.TrimSuffix(repo, "/")
+	switch r.Method {
+	case http.MethodHead, http.MethodGet:
+		body, desc, ok := s.Index.Manifest(repo, ref)
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest not found", ref)
+			return
+		}
+		w.Header().Set("Content-Type", desc.MediaType)
+		w.Header().Set("Docker-Content-Digest", desc.Digest)
+		w.Header().Set("Content-Length", strconv.FormatInt(desc.Size, 10))
+		w.WriteHeader(http.StatusOK)
+		if r.Method == http.MethodGet {
+			w.Write(body)
+		}
+	case http.MethodPut:
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "MANIFEST_INVALID", "failed to read manifest body", err.Error())
+			return
+		}
+		digest, err := s.Index.PutManifest(repo, ref, body)
+		if err != nil {
+			s.writeError(w, http.StatusBadRequest, "MANIFEST_INVALID", "failed to store manifest", err.Error())
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s/manifests/%s", repo, ref))
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		if err := s.Index.DeleteManifest(repo, ref); err != nil {
+			s.writeError(w, http.StatusNotFound, "MANIFEST_UNKNOWN", "manifest not found", err.Error())
+			return
+		}
+		w.WriteHeader(http.StatusAccepted)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "method not allowed", r.Method)
+	}
+}
+
+// handleBlob implements HEAD/GET /v2/<name>/blobs/<digest>.
+func (s *Server) handleBlob(w http.ResponseWriter, r *http.Request, repo, digest string) {
+	repo = strings.TrimSuffix(repo, "/")
+	switch r.Method {
+	case http.MethodHead:
+		size, ok := s.Index.BlobExists(repo, digest)
+		if !ok {
+			s.writeError(w, http.StatusNotFound, "BLOB_UNKNOWN", "blob not found", digest)
+			return
+		}
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.WriteHeader(http.StatusOK)
+	case http.MethodGet:
+		rc, size, err := s.Index.OpenBlob(repo, digest)
+		if err != nil {
+			s.writeError(w, http.StatusNotFound, "BLOB_UNKNOWN", "blob not found", digest)
+			return
+		}
+		defer rc.Close()
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.Header().Set("Content-Length", strconv.FormatInt(size, 10))
+		w.Header().Set("Accept-Ranges", "bytes")
+		if rng := r.Header.Get("Range"); rng != "" {
+			s.serveRange(w, rc, size, rng)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		io.Copy(w, rc)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "method not allowed", r.Method)
+	}
+}
+
+// serveRange serves a single "Range: bytes=start-end" request, as used by
+// resumable/chunked pulls.
+func (s *Server) serveRange(w http.ResponseWriter, rc io.Reader, size int64, rangeHeader string) {
+	var start, end int64
+	spec := strings.TrimPrefix(rangeHeader, "bytes=")
+	parts := strings.SplitN(spec, "-", 2)
+	start, _ = strconv.ParseInt(parts[0], 10, 64)
+	if len(parts) > 1 && parts[1] != "" {
+		end, _ = strconv.ParseInt(parts[1], 10, 64)
+	} else {
+		end = size - 1
+	}
+	if start > 0 {
+		io.CopyN(io.Discard, rc, start)
+	}
+	w.Header().Set("Content-Range", fmt.Sprintf("bytes %d-%d/%d", start, end, size))
+	w.Header().Set("Content-Length", strconv.FormatInt(end-start+1, 10))
+	w.WriteHeader(http.StatusPartialContent)
+	io.CopyN(w, rc, end-start+1)
+}
+
+// handleUploads implements the chunked upload sequence:
+// POST /v2/<name>/blobs/uploads/ starts a session,
+// PATCH /v2/<name>/blobs/uploads/<id> appends a chunk,
+// PUT /v2/<name>/blobs/uploads/<id>?digest=... finalises it.
+func (s *Server) handleUploads(w http.ResponseWriter, r *http.Request, repo, id string) {
+	repo = strings.TrimSuffix(repo, "/")
+	id = strings.TrimSuffix(id, "/")
+	switch r.Method {
+	case http.MethodPost:
+		sessionID, err := s.uploads.start(repo)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, "BLOB_UPLOAD_INVALID", "failed to start upload", err.Error())
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%s", repo, sessionID))
+		w.Header().Set("Range", "0-0")
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodPatch:
+		offset, err := s.uploads.append(id, r.Body)
+		if err != nil {
+			s.writeError(w, http.StatusNotFound, "BLOB_UPLOAD_UNKNOWN", "unknown upload session", err.Error())
+			return
+		}
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/uploads/%s", repo, id))
+		w.Header().Set("Range", fmt.Sprintf("0-%d", offset-1))
+		w.WriteHeader(http.StatusAccepted)
+	case http.MethodPut:
+		if r.ContentLength > 0 {
+			if _, err := s.uploads.append(id, r.Body); err != nil {
+				s.writeError(w, http.StatusNotFound, "BLOB_UPLOAD_UNKNOWN", "unknown upload session", err.Error())
+				return
+			}
+		}
+		path, digest, size, err := s.uploads.finish(id)
+		if err != nil {
+			s.writeError(w, http.StatusNotFound, "BLOB_UPLOAD_UNKNOWN", "unknown upload session", err.Error())
+			return
+		}
+		wantDigest := r.URL.Query().Get("digest")
+		if wantDigest != "" && wantDigest != digest {
+			s.writeError(w, http.StatusBadRequest, "DIGEST_INVALID", "uploaded content does not match digest", fmt.Sprintf("expected %s got %s", wantDigest, digest))
+			return
+		}
+		f, err := openStagedBlob(path)
+		if err != nil {
+			s.writeError(w, http.StatusInternalServerError, "BLOB_UPLOAD_INVALID", "failed to read staged blob", err.Error())
+			return
+		}
+		defer f.Close()
+		if err := s.Index.PutBlob(repo, digest, size, f); err != nil {
+			s.writeError(w, http.StatusInternalServerError, "BLOB_UPLOAD_INVALID", "failed to store blob", err.Error())
+			return
+		}
+		removeStagedBlob(path)
+		w.Header().Set("Docker-Content-Digest", digest)
+		w.Header().Set("Location", fmt.Sprintf("/v2/%s/blobs/%s", repo, digest))
+		w.WriteHeader(http.StatusCreated)
+	case http.MethodDelete:
+		s.uploads.cancel(id)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		s.writeError(w, http.StatusMethodNotAllowed, "UNSUPPORTED", "method not allowed", r.Method)
+	}
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func (s *Server) writeError(w http.ResponseWriter, status int, code, message, detail string) {
+	s.writeJSON(w, status, ErrorResponse{Errors: []ErrorInfo{{Code: code, Message: message, Detail: detail}}})
+}
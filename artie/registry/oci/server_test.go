@@ -0,0 +1,306 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package oci
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeIndex is a minimal in-memory Index used to drive Server's handlers in
+// isolation, without a real FileRegistry.
+type fakeIndex struct {
+	mu        sync.Mutex
+	manifests map[string][]byte // "repo/ref" -> body
+	blobs     map[string][]byte // "repo/digest" -> content
+}
+
+func newFakeIndex() *fakeIndex {
+	return &fakeIndex{manifests: map[string][]byte{}, blobs: map[string][]byte{}}
+}
+
+func (f *fakeIndex) Repositories() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	seen := map[string]bool{}
+	var repos []string
+	for key := range f.manifests {
+		repo := strings.SplitN(key, "/", 2)[0]
+		if !seen[repo] {
+			seen[repo] = true
+			repos = append(repos, repo)
+		}
+	}
+	return repos
+}
+
+func (f *fakeIndex) Manifest(repo, ref string) ([]byte, Descriptor, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	body, ok := f.manifests[repo+"/"+ref]
+	if !ok {
+		return nil, Descriptor{}, false
+	}
+	digest := sha256Digest(body)
+	return body, Descriptor{MediaType: MediaTypeManifest, Digest: digest, Size: int64(len(body))}, true
+}
+
+func (f *fakeIndex) PutManifest(repo, ref string, body []byte) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	digest := sha256Digest(body)
+	f.manifests[repo+"/"+ref] = body
+	f.manifests[repo+"/"+digest] = body
+	return digest, nil
+}
+
+func (f *fakeIndex) DeleteManifest(repo, ref string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if _, ok := f.manifests[repo+"/"+ref]; !ok {
+		return os.ErrNotExist
+	}
+	delete(f.manifests, repo+"/"+ref)
+	return nil
+}
+
+func (f *fakeIndex) BlobExists(repo, digest string) (int64, bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	b, ok := f.blobs[repo+"/"+digest]
+	return int64(len(b)), ok
+}
+
+func (f *fakeIndex) OpenBlob(repo, digest string) (io.ReadCloser, int64, error) {
+	f.mu.Lock()
+	b, ok := f.blobs[repo+"/"+digest]
+	f.mu.Unlock()
+	if !ok {
+		return nil, 0, os.ErrNotExist
+	}
+	return ioutil.NopCloser(bytes.NewReader(b)), int64(len(b)), nil
+}
+
+func (f *fakeIndex) PutBlob(repo, digest string, size int64, r io.Reader) error {
+	b, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if int64(len(b)) != size {
+		return io.ErrUnexpectedEOF
+	}
+	if sha256Digest(b) != digest {
+		return io.ErrUnexpectedEOF
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.blobs[repo+"/"+digest] = b
+	return nil
+}
+
+func sha256Digest(b []byte) string {
+	h := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(h[:])
+}
+
+func newTestServer(t *testing.T) (*httptest.Server, *fakeIndex) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "artie-oci-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	index := newFakeIndex()
+	return httptest.NewServer(NewServer(index, dir)), index
+}
+
+func TestHandleBase(t *testing.T) {
+	srv, _ := newTestServer(t)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/v2/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Errorf("expected 200, got %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Docker-Distribution-Api-Version") != "registry/2.0" {
+		t.Errorf("expected the distribution api version header to be set")
+	}
+}
+
+func TestManifestPutGetRoundTrip(t *testing.T) {
+	srv, _ := newTestServer(t)
+	defer srv.Close()
+	body := []byte(`{"schemaVersion":2}`)
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+"/v2/lib/sample/manifests/v1", bytes.NewReader(body))
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 from manifest PUT, got %d", resp.StatusCode)
+	}
+	wantDigest := resp.Header.Get("Docker-Content-Digest")
+	if wantDigest == "" {
+		t.Fatal("expected Docker-Content-Digest header on PUT response")
+	}
+
+	resp, err = http.Get(srv.URL + "/v2/lib/sample/manifests/v1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from manifest GET, got %d", resp.StatusCode)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	if !bytes.Equal(got, body) {
+		t.Errorf("expected manifest body to round-trip, got %s", got)
+	}
+	if resp.Header.Get("Docker-Content-Digest") != wantDigest {
+		t.Errorf("expected GET digest to match the one returned by PUT")
+	}
+}
+
+func TestManifestGetUnknownReturns404(t *testing.T) {
+	srv, _ := newTestServer(t)
+	defer srv.Close()
+	resp, err := http.Get(srv.URL + "/v2/lib/sample/manifests/missing")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("expected 404 for an unknown manifest, got %d", resp.StatusCode)
+	}
+}
+
+// TestBlobUploadSequence drives the full chunked upload dance -- POST to start
+// a session, PATCH to append a chunk, PUT to finalise it -- and then reads the
+// blob back, exercising handleUploads and handleBlob together.
+func TestBlobUploadSequence(t *testing.T) {
+	srv, index := newTestServer(t)
+	defer srv.Close()
+	content := []byte("artefact zip content")
+	digest := sha256Digest(content)
+
+	resp, err := http.Post(srv.URL+"/v2/lib/sample/blobs/uploads/", "application/octet-stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202 from upload start, got %d", resp.StatusCode)
+	}
+	location := resp.Header.Get("Location")
+	if location == "" {
+		t.Fatal("expected a Location header from upload start")
+	}
+
+	req, _ := http.NewRequest(http.MethodPatch, srv.URL+location, bytes.NewReader(content))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusAccepted {
+		t.Fatalf("expected 202 from upload PATCH, got %d", resp.StatusCode)
+	}
+
+	req, _ = http.NewRequest(http.MethodPut, srv.URL+location+"?digest="+digest, nil)
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201 from upload finalise, got %d", resp.StatusCode)
+	}
+
+	if _, ok := index.BlobExists("lib/sample", digest); !ok {
+		t.Fatalf("expected blob %s to be stored in the index", digest)
+	}
+
+	resp, err = http.Get(srv.URL + "/v2/lib/sample/blobs/" + digest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 from blob GET, got %d", resp.StatusCode)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	if !bytes.Equal(got, content) {
+		t.Errorf("expected downloaded blob to match uploaded content, got %q", got)
+	}
+}
+
+// TestBlobUploadDigestMismatchRejected ensures a finalised upload whose
+// content does not hash to the declared digest is rejected, rather than
+// silently stored under the wrong key.
+func TestBlobUploadDigestMismatchRejected(t *testing.T) {
+	srv, _ := newTestServer(t)
+	defer srv.Close()
+
+	resp, err := http.Post(srv.URL+"/v2/lib/sample/blobs/uploads/", "application/octet-stream", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	location := resp.Header.Get("Location")
+
+	req, _ := http.NewRequest(http.MethodPut, srv.URL+location+"?digest=sha256:0000000000000000000000000000000000000000000000000000000000000000", strings.NewReader("content"))
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 on digest mismatch, got %d", resp.StatusCode)
+	}
+}
+
+// TestBlobGetRange exercises the ranged GET path used by resumable/chunked
+// pulls.
+func TestBlobGetRange(t *testing.T) {
+	srv, index := newTestServer(t)
+	defer srv.Close()
+	content := []byte("0123456789")
+	digest := sha256Digest(content)
+	if err := index.PutBlob("lib/sample", digest, int64(len(content)), bytes.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/v2/lib/sample/blobs/"+digest, nil)
+	req.Header.Set("Range", "bytes=2-5")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("expected 206 for a ranged GET, got %d", resp.StatusCode)
+	}
+	got, _ := io.ReadAll(resp.Body)
+	if string(got) != "2345" {
+		t.Errorf("expected range bytes=2-5 to return \"2345\", got %q", got)
+	}
+}
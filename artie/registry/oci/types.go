@@ -0,0 +1,67 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+
+// Package oci implements the subset of the OCI Distribution Specification v2
+// (https://github.com/opencontainers/distribution-spec) required for Artie
+// artefacts to be pushed to and pulled from any OCI-compliant registry.
+package oci
+
+const (
+	// MediaTypeManifest is the media type of the manifest describing an Artie artefact.
+	MediaTypeManifest = "application/vnd.oci.image.manifest.v1+json"
+	// MediaTypeArtefact is the custom artifactType recorded in the manifest to identify
+	// the payload as an Artie artefact rather than a container image.
+	MediaTypeArtefact = "application/vnd.gatblau.artie.manifest.v1+json"
+	// MediaTypeConfig is a media type for an empty config blob. The manifests
+	// built by this project do not use it: their config blob is the seal JSON,
+	// recorded with MediaTypeSeal.
+	MediaTypeConfig = "application/vnd.gatblau.artie.config.v1+json"
+	// MediaTypeLayer is the media type of the zip artefact stored as a single layer.
+	MediaTypeLayer = "application/vnd.gatblau.artie.layer.v1.zip"
+	// MediaTypeSeal is the media type of the seal JSON stored as the manifest's config blob.
+	MediaTypeSeal = "application/vnd.gatblau.artie.seal.v1+json"
+)
+
+// Descriptor uniquely identifies content addressable by digest, as defined by the
+// OCI image spec.
+type Descriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+	Size      int64  `json:"size"`
+}
+
+// Manifest is the OCI image manifest used to describe an Artie artefact.
+// The zip artefact is recorded as the single layer and the seal JSON is recorded
+// as the config blob, so that any OCI-compliant client can pull both with the
+// standard blob endpoints.
+type Manifest struct {
+	SchemaVersion int               `json:"schemaVersion"`
+	MediaType     string            `json:"mediaType"`
+	ArtifactType  string            `json:"artifactType,omitempty"`
+	Config        Descriptor        `json:"config"`
+	Layers        []Descriptor      `json:"layers"`
+	Annotations   map[string]string `json:"annotations,omitempty"`
+}
+
+// TagList is the response body of the tags list endpoint.
+type TagList struct {
+	Name string   `json:"name"`
+	Tags []string `json:"tags"`
+}
+
+// ErrorResponse is the standard OCI distribution error envelope.
+type ErrorResponse struct {
+	Errors []ErrorInfo `json:"errors"`
+}
+
+// ErrorInfo is a single error within an ErrorResponse.
+type ErrorInfo struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Detail  string `json:"detail,omitempty"`
+}
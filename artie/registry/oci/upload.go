@@ -0,0 +1,137 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package oci
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"sync"
+)
+
+// upload tracks the state of an in-progress chunked blob upload, as started by
+// POST /v2/<name>/blobs/uploads/ and continued with one or more PATCH requests.
+type upload struct {
+	repo   string
+	file   *os.File
+	hash   hashWriter
+	offset int64
+}
+
+// hashWriter is the subset of hash.Hash used while streaming an upload to disk.
+type hashWriter interface {
+	io.Writer
+	Sum(b []byte) []byte
+}
+
+// uploads tracks in-flight uploads by session id. A session is created on POST
+// and discarded once PUT finalises (or the registry is restarted).
+type uploads struct {
+	mu   sync.Mutex
+	byID map[string]*upload
+	dir  string
+}
+
+func newUploads(tmpDir string) *uploads {
+	return &uploads{byID: make(map[string]*upload), dir: tmpDir}
+}
+
+// start creates a new upload session for repo and returns its id.
+func (u *uploads) start(repo string) (string, error) {
+	if err := os.MkdirAll(u.dir, 0755); err != nil {
+		return "", err
+	}
+	id := newUploadID()
+	f, err := os.Create(u.sessionFile(id))
+	if err != nil {
+		return "", err
+	}
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	u.byID[id] = &upload{repo: repo, file: f, hash: sha256.New()}
+	return id, nil
+}
+
+func (u *uploads) sessionFile(id string) string {
+	return fmt.Sprintf("%s/%s.upload", u.dir, id)
+}
+
+// get returns the upload session for id, if any.
+func (u *uploads) get(id string) (*upload, bool) {
+	u.mu.Lock()
+	defer u.mu.Unlock()
+	up, ok := u.byID[id]
+	return up, ok
+}
+
+// append writes a chunk to the end of the upload session's staged content,
+// returning the new total size. Chunks are appended in the order they arrive;
+// a Content-Range sent with a chunk is not checked against the current offset.
+func (u *uploads) append(id string, r io.Reader) (int64, error) {
+	up, ok := u.get(id)
+	if !ok {
+		return 0, fmt.Errorf("unknown upload session %s", id)
+	}
+	n, err := io.Copy(io.MultiWriter(up.file, up.hash), r)
+	if err != nil {
+		return 0, err
+	}
+	up.offset += n
+	return up.offset, nil
+}
+
+// finish closes and removes the upload session, returning the path of the
+// staged content and the digest computed while streaming it.
+func (u *uploads) finish(id string) (path, digest string, size int64, err error) {
+	up, ok := u.get(id)
+	if !ok {
+		return "", "", 0, fmt.Errorf("unknown upload session %s", id)
+	}
+	u.mu.Lock()
+	delete(u.byID, id)
+	u.mu.Unlock()
+	if err := up.file.Close(); err != nil {
+		return "", "", 0, err
+	}
+	return up.file.Name(), "sha256:" + hex.EncodeToString(up.hash.Sum(nil)), up.offset, nil
+}
+
+// cancel discards an upload session and its staged content.
+func (u *uploads) cancel(id string) {
+	up, ok := u.get(id)
+	if !ok {
+		return
+	}
+	u.mu.Lock()
+	delete(u.byID, id)
+	u.mu.Unlock()
+	up.file.Close()
+	os.Remove(up.file.Name())
+}
+
+// newUploadID generates a random session id without pulling in a uuid dependency.
+func newUploadID() string {
+	b := make([]byte, 16)
+	f, err := os.Open("/dev/urandom")
+	if err == nil {
+		defer f.Close()
+		io.ReadFull(f, b)
+	} else {
+		// fall back to a weak but available source if /dev/urandom is not present
+		t, _ := ioutil.TempFile("", "upload")
+		if t != nil {
+			copy(b, []byte(t.Name()))
+			t.Close()
+			os.Remove(t.Name())
+		}
+	}
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,295 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package registry
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/gatblau/onix/artie/core"
+	"github.com/gatblau/onix/artie/registry/oci"
+)
+
+// ociIndex adapts a FileRegistry to the oci.Index interface so it can be
+// served over the OCI Distribution Spec v2 API by oci.Server.
+type ociIndex struct {
+	reg *FileRegistry
+}
+
+// OCIServer returns an OCI Distribution Spec v2 compliant HTTP handler backed
+// by this FileRegistry, so that Artie artefacts can be pushed and pulled by
+// any OCI-compliant client.
+func (r *FileRegistry) OCIServer() *oci.Server {
+	return oci.NewServer(&ociIndex{reg: r}, filepath.Join(r.Path(), ".uploads"))
+}
+
+func (i *ociIndex) Repositories() []string {
+	names := make([]string, 0, len(i.reg.Repositories))
+	for _, repo := range i.reg.Repositories {
+		names = append(names, repo.Repository)
+	}
+	return names
+}
+
+func (i *ociIndex) Tags(repo string) []string {
+	tags := make([]string, 0)
+	for _, r := range i.reg.Repositories {
+		if r.Repository != repo {
+			continue
+		}
+		for _, a := range r.Artefacts {
+			tags = append(tags, a.Tags...)
+		}
+	}
+	return tags
+}
+
+// findArtefact locates the artefact within repo matching ref, which can be
+// either a tag or a "sha256:..." manifest digest.
+func (i *ociIndex) findArtefact(repo, ref string) (*artefact, bool) {
+	for _, r := range i.reg.Repositories {
+		if r.Repository != repo {
+			continue
+		}
+		for _, a := range r.Artefacts {
+			if a.HasTag(ref) {
+				return a, true
+			}
+			if strings.HasPrefix(ref, "sha256:") {
+				if body, _ := i.manifestBytes(a); body != nil && digestOf(body) == ref {
+					return a, true
+				}
+			}
+		}
+	}
+	return nil, false
+}
+
+func (i *ociIndex) Manifest(repo, ref string) ([]byte, oci.Descriptor, bool) {
+	a, ok := i.findArtefact(repo, ref)
+	if !ok {
+		return nil, oci.Descriptor{}, false
+	}
+	body, err := i.manifestBytes(a)
+	if err != nil {
+		return nil, oci.Descriptor{}, false
+	}
+	return body, oci.Descriptor{MediaType: oci.MediaTypeManifest, Digest: digestOf(body), Size: int64(len(body))}, true
+}
+
+// manifestBytes builds the OCI manifest document for an artefact on the fly:
+// the seal JSON is recorded as the config blob and the zip file as the single
+// layer, so neither needs to be duplicated in blob storage.
+func (i *ociIndex) manifestBytes(a *artefact) ([]byte, error) {
+	sealBytes, err := i.readSeal(a)
+	if err != nil {
+		return nil, err
+	}
+	zipSize, err := i.reg.statBlob(i.reg.zipKey(a.FileRef))
+	if err != nil {
+		return nil, err
+	}
+	manifest := oci.Manifest{
+		SchemaVersion: 2,
+		MediaType:     oci.MediaTypeManifest,
+		ArtifactType:  oci.MediaTypeArtefact,
+		Config: oci.Descriptor{
+			MediaType: oci.MediaTypeSeal,
+			Digest:    digestOf(sealBytes),
+			Size:      int64(len(sealBytes)),
+		},
+		Layers: []oci.Descriptor{
+			{
+				MediaType: oci.MediaTypeLayer,
+				Digest:    a.Id,
+				Size:      zipSize,
+			},
+		},
+		Annotations: map[string]string{
+			"org.opencontainers.image.created": a.Created,
+			"gatblau.artie.type":               a.Type,
+		},
+	}
+	return json.Marshal(manifest)
+}
+
+// readSeal returns the seal JSON bytes for an already registered artefact.
+func (i *ociIndex) readSeal(a *artefact) ([]byte, error) {
+	rc, _, err := i.reg.openBlob(i.reg.sealKey(a.FileRef))
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return ioutil.ReadAll(rc)
+}
+
+// PutManifest registers a manifest received from an OCI client against repo
+// and ref, adopting the layer and config blobs it references (already staged
+// locally via the blob upload endpoints) into the configured blob storage
+// backend.
+func (i *ociIndex) PutManifest(repo, ref string, body []byte) (string, error) {
+	var manifest oci.Manifest
+	if err := json.Unmarshal(body, &manifest); err != nil {
+		return "", fmt.Errorf("invalid manifest: %w", err)
+	}
+	if len(manifest.Layers) == 0 {
+		return "", fmt.Errorf("manifest has no layers")
+	}
+	layer := manifest.Layers[0]
+	fileRef := strings.TrimPrefix(layer.Digest, "sha256:")
+	stagedZip := i.stagingPath(layer.Digest)
+	if !fileExists(stagedZip) {
+		return "", fmt.Errorf("layer blob %s not found, upload it first", layer.Digest)
+	}
+	if err := i.reg.putBlob(stagedZip, i.reg.zipKey(fileRef)); err != nil {
+		return "", err
+	}
+	if stagedSeal := i.stagingPath(manifest.Config.Digest); fileExists(stagedSeal) {
+		if err := i.reg.putBlob(stagedSeal, i.reg.sealKey(fileRef)); err != nil {
+			return "", err
+		}
+	}
+	repoEntry := i.repositoryByName(repo)
+	if repoEntry == nil {
+		repoEntry = &repository{Repository: repo, Artefacts: make([]*artefact, 0)}
+		i.reg.Repositories = append(i.reg.Repositories, repoEntry)
+	}
+	repoEntry.Artefacts = append(repoEntry.Artefacts, &artefact{
+		Id:      layer.Digest,
+		Type:    manifest.Annotations["gatblau.artie.type"],
+		FileRef: fileRef,
+		Tags:    []string{ref},
+		Size:    fmt.Sprintf("%d", layer.Size),
+		Created: manifest.Annotations["org.opencontainers.image.created"],
+	})
+	i.reg.save()
+	return digestOf(body), nil
+}
+
+func (i *ociIndex) DeleteManifest(repo, ref string) error {
+	a, ok := i.findArtefact(repo, ref)
+	if !ok {
+		return fmt.Errorf("manifest %s not found in %s", ref, repo)
+	}
+	a.Tags = core.RemoveElement(a.Tags, ref)
+	i.reg.save()
+	return nil
+}
+
+func (i *ociIndex) repositoryByName(name string) *repository {
+	for _, r := range i.reg.Repositories {
+		if r.Repository == name {
+			return r
+		}
+	}
+	return nil
+}
+
+// stagingPath returns the local scratch location of a blob that has been
+// pushed by an OCI client but not yet attached to a manifest. Blobs are
+// always staged locally, regardless of the configured backend, since a
+// manifest (and so the blob's final key) is not known until the PUT
+// manifest request that follows.
+func (i *ociIndex) stagingPath(digest string) string {
+	return filepath.Join(i.reg.Path(), ".staging", strings.ReplaceAll(digest, ":", "_"))
+}
+
+func (i *ociIndex) BlobExists(repo, digest string) (int64, bool) {
+	if fileExists(i.stagingPath(digest)) {
+		info, err := os.Stat(i.stagingPath(digest))
+		if err == nil {
+			return info.Size(), true
+		}
+	}
+	if rc, size, err := i.resolveBlob(repo, digest); err == nil {
+		rc.Close()
+		return size, true
+	}
+	return 0, false
+}
+
+func (i *ociIndex) OpenBlob(repo, digest string) (io.ReadCloser, int64, error) {
+	if fileExists(i.stagingPath(digest)) {
+		f, err := os.Open(i.stagingPath(digest))
+		if err != nil {
+			return nil, 0, err
+		}
+		info, err := f.Stat()
+		if err != nil {
+			f.Close()
+			return nil, 0, err
+		}
+		return f, info.Size(), nil
+	}
+	return i.resolveBlob(repo, digest)
+}
+
+// resolveBlob finds a blob already committed to a registered artefact,
+// whether it is the zip layer (identified by artefact.Id) or the seal config
+// blob (identified by the digest of its content).
+func (i *ociIndex) resolveBlob(repo, digest string) (io.ReadCloser, int64, error) {
+	for _, r := range i.reg.Repositories {
+		if r.Repository != repo {
+			continue
+		}
+		for _, a := range r.Artefacts {
+			if a.Id == digest {
+				return i.reg.openBlob(i.reg.zipKey(a.FileRef))
+			}
+			if sealBytes, err := i.readSeal(a); err == nil && digestOf(sealBytes) == digest {
+				return ioutil.NopCloser(bytes.NewReader(sealBytes)), int64(len(sealBytes)), nil
+			}
+		}
+	}
+	return nil, 0, fmt.Errorf("blob %s not found", digest)
+}
+
+func (i *ociIndex) PutBlob(repo, digest string, size int64, r io.Reader) error {
+	path := i.stagingPath(digest)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	h := sha256.New()
+	n, err := io.Copy(io.MultiWriter(f, h), r)
+	if err != nil {
+		return err
+	}
+	if n != size {
+		os.Remove(path)
+		return fmt.Errorf("blob size mismatch: expected %d bytes, got %d", size, n)
+	}
+	if got := "sha256:" + hex.EncodeToString(h.Sum(nil)); got != digest {
+		os.Remove(path)
+		return fmt.Errorf("blob digest mismatch: expected %s, got %s", digest, got)
+	}
+	return nil
+}
+
+// digestOf returns the sha256 digest of b in "sha256:<hex>" form.
+func digestOf(b []byte) string {
+	sum := sha256.Sum256(b)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
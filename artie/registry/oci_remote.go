@@ -0,0 +1,337 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package registry
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gatblau/onix/artie/core"
+	"github.com/gatblau/onix/artie/registry/oci"
+	"github.com/gatblau/onix/artie/registry/progress"
+)
+
+// OCIRemote is a Remote implementation that speaks the OCI Distribution Spec
+// v2 protocol, so artefacts can be pushed to and pulled from any OCI compliant
+// registry (this project's own oci.Server included).
+type OCIRemote struct {
+	// Uri is the base address of the remote registry, e.g. https://artregistry.gatblau.org
+	Uri string
+}
+
+// UploadArtefact pushes zip and seal, read from the registry's configured
+// blob storage backend, as the layer and config blobs of an OCI manifest
+// tagged with name.Tag in the repository identified by name.
+func (remote *OCIRemote) UploadArtefact(client *http.Client, name *core.ArtieName, zip, seal io.Reader, credentials string, opts TransferOptions) error {
+	credentials = remote.resolveCredentials(credentials)
+	zipBytes, err := ioutil.ReadAll(zip)
+	if err != nil {
+		return err
+	}
+	sealBytes, err := ioutil.ReadAll(seal)
+	if err != nil {
+		return err
+	}
+	repo := name.FullyQualifiedName()
+	reporter := progress.NewReporter(fmt.Sprintf("pushing %s", name), int64(len(zipBytes)+len(sealBytes)), opts.Quiet)
+	layerDigest := digestOf(zipBytes)
+	if err = remote.pushBlob(client, repo, layerDigest, zipBytes, credentials, reporter); err != nil {
+		return err
+	}
+	configDigest := digestOf(sealBytes)
+	if err = remote.pushBlob(client, repo, configDigest, sealBytes, credentials, reporter); err != nil {
+		return err
+	}
+	reporter.Done()
+	manifest := oci.Manifest{
+		SchemaVersion: 2,
+		MediaType:     oci.MediaTypeManifest,
+		ArtifactType:  oci.MediaTypeArtefact,
+		Config:        oci.Descriptor{MediaType: oci.MediaTypeSeal, Digest: configDigest, Size: int64(len(sealBytes))},
+		Layers:        []oci.Descriptor{{MediaType: oci.MediaTypeLayer, Digest: layerDigest, Size: int64(len(zipBytes))}},
+	}
+	manifestBytes, err := json.Marshal(manifest)
+	if err != nil {
+		return err
+	}
+	return remote.putManifest(client, repo, name.Tag, manifestBytes, credentials)
+}
+
+// DownloadArtefact fetches the manifest tagged with name.Tag, the seal in
+// full and the zip blob in resumable, parallel chunks, and writes them to
+// targetDir as <fileRef>.json/.zip ready for the FileRegistry to register
+// with Add. A zip download interrupted midway leaves behind a <fileRef>.zip.part
+// file that is resumed from on the next Pull of the same artefact.
+func (remote *OCIRemote) DownloadArtefact(client *http.Client, name *core.ArtieName, targetDir, credentials string, opts TransferOptions) (fileRef string, err error) {
+	credentials = remote.resolveCredentials(credentials)
+	repo := name.FullyQualifiedName()
+	manifestBytes, err := remote.getManifest(client, repo, name.Tag, credentials)
+	if err != nil {
+		return "", err
+	}
+	var manifest oci.Manifest
+	if err = json.Unmarshal(manifestBytes, &manifest); err != nil {
+		return "", err
+	}
+	if len(manifest.Layers) == 0 {
+		return "", fmt.Errorf("manifest for %s has no layers", name)
+	}
+	layer := manifest.Layers[0]
+	fileRef = strings.TrimPrefix(layer.Digest, "sha256:")
+	sealBytes, err := remote.getBlob(client, repo, manifest.Config.Digest, credentials)
+	if err != nil {
+		return "", err
+	}
+	if err = os.MkdirAll(targetDir, 0755); err != nil {
+		return "", err
+	}
+	zipPath := fmt.Sprintf("%s/%s.zip", targetDir, fileRef)
+	reporter := progress.NewReporter(fmt.Sprintf("pulling %s", name), layer.Size, opts.Quiet)
+	if err = remote.downloadChunked(client, repo, layer.Digest, layer.Size, zipPath, credentials, opts, reporter); err != nil {
+		return "", err
+	}
+	reporter.Done()
+	if digest, verr := fileDigest(zipPath); verr != nil || digest != layer.Digest {
+		return "", fmt.Errorf("downloaded zip blob failed digest verification: expected %s", layer.Digest)
+	}
+	if err = ioutil.WriteFile(fmt.Sprintf("%s/%s.json", targetDir, fileRef), sealBytes, 0644); err != nil {
+		return "", err
+	}
+	return fileRef, nil
+}
+
+// downloadChunked fetches digest (size bytes long) from repo into destPath,
+// using opts.Parallelism workers each requesting opts.ChunkSize ranges. Any
+// bytes already present in a matching destPath+".part" file from a previous,
+// interrupted attempt are not re-downloaded.
+func (remote *OCIRemote) downloadChunked(client *http.Client, repo, digest string, size int64, destPath, credentials string, opts TransferOptions, reporter *progress.Reporter) error {
+	partPath := destPath + ".part"
+	f, err := os.OpenFile(partPath, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	resumeOffset := info.Size()
+	// a stale, oversized .part file cannot belong to this blob; start over
+	if resumeOffset > size {
+		resumeOffset = 0
+	} else if resumeOffset > 0 {
+		reporter.Add(resumeOffset)
+	}
+	if err = f.Truncate(size); err != nil {
+		return err
+	}
+
+	type chunk struct{ start, end int64 }
+	chunks := make([]chunk, 0)
+	for start := resumeOffset; start < size; start += opts.ChunkSize {
+		end := start + opts.ChunkSize - 1
+		if end >= size {
+			end = size - 1
+		}
+		chunks = append(chunks, chunk{start, end})
+	}
+
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		firstErr error
+	)
+	sem := make(chan struct{}, opts.Parallelism)
+	for _, c := range chunks {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(c chunk) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			if err := remote.downloadRange(client, repo, digest, c.start, c.end, f, credentials, reporter); err != nil {
+				mu.Lock()
+				if firstErr == nil {
+					firstErr = err
+				}
+				mu.Unlock()
+			}
+		}(c)
+	}
+	wg.Wait()
+	if firstErr != nil {
+		return firstErr
+	}
+	if err = f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(partPath, destPath)
+}
+
+// downloadRange fetches bytes [start, end] of digest and writes them to f at
+// offset start.
+func (remote *OCIRemote) downloadRange(client *http.Client, repo, digest string, start, end int64, f *os.File, credentials string, reporter *progress.Reporter) error {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v2/%s/blobs/%s", remote.Uri, repo, digest), nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Range", fmt.Sprintf("bytes=%d-%d", start, end))
+	remote.authorize(req, credentials)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusPartialContent && resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("failed to fetch blob %s range %d-%d: %s", digest, start, end, resp.Status)
+	}
+	sw := &offsetWriter{f: f, offset: start}
+	n, err := io.Copy(sw, progress.NewReader(resp.Body, reporter))
+	if err != nil {
+		return err
+	}
+	if n != end-start+1 {
+		return fmt.Errorf("short read for blob %s range %d-%d: got %d bytes", digest, start, end, n)
+	}
+	return nil
+}
+
+// offsetWriter writes sequential Write calls to f starting at offset,
+// advancing as it goes -- used to land a ranged chunk download at its
+// correct position within the destination file.
+type offsetWriter struct {
+	f      *os.File
+	offset int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.f.WriteAt(p, w.offset)
+	w.offset += int64(n)
+	return n, err
+}
+
+func (remote *OCIRemote) pushBlob(client *http.Client, repo, digest string, content []byte, credentials string, reporter *progress.Reporter) error {
+	startReq, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s/v2/%s/blobs/uploads/", remote.Uri, repo), nil)
+	if err != nil {
+		return err
+	}
+	remote.authorize(startReq, credentials)
+	startResp, err := client.Do(startReq)
+	if err != nil {
+		return err
+	}
+	startResp.Body.Close()
+	if startResp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("failed to start blob upload: %s", startResp.Status)
+	}
+	location := startResp.Header.Get("Location")
+	putReq, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s%s?digest=%s", remote.Uri, location, digest), progress.NewReader(bytes.NewReader(content), reporter))
+	if err != nil {
+		return err
+	}
+	putReq.ContentLength = int64(len(content))
+	remote.authorize(putReq, credentials)
+	putResp, err := client.Do(putReq)
+	if err != nil {
+		return err
+	}
+	defer putResp.Body.Close()
+	if putResp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to upload blob %s: %s", digest, putResp.Status)
+	}
+	return nil
+}
+
+func (remote *OCIRemote) putManifest(client *http.Client, repo, tag string, body []byte, credentials string) error {
+	req, err := http.NewRequest(http.MethodPut, fmt.Sprintf("%s/v2/%s/manifests/%s", remote.Uri, repo, tag), bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", oci.MediaTypeManifest)
+	remote.authorize(req, credentials)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusCreated {
+		return fmt.Errorf("failed to upload manifest for %s:%s: %s", repo, tag, resp.Status)
+	}
+	return nil
+}
+
+func (remote *OCIRemote) getManifest(client *http.Client, repo, ref, credentials string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v2/%s/manifests/%s", remote.Uri, repo, ref), nil)
+	if err != nil {
+		return nil, err
+	}
+	remote.authorize(req, credentials)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch manifest for %s:%s: %s", repo, ref, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+func (remote *OCIRemote) getBlob(client *http.Client, repo, digest, credentials string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%s/v2/%s/blobs/%s", remote.Uri, repo, digest), nil)
+	if err != nil {
+		return nil, err
+	}
+	remote.authorize(req, credentials)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to fetch blob %s: %s", digest, resp.Status)
+	}
+	return ioutil.ReadAll(resp.Body)
+}
+
+// authorize attaches the basic auth credentials to an OCI API request, if any
+// were provided.
+func (remote *OCIRemote) authorize(req *http.Request, credentials string) {
+	if credentials == "" {
+		return
+	}
+	parts := strings.SplitN(credentials, ":", 2)
+	if len(parts) == 2 {
+		req.SetBasicAuth(parts[0], parts[1])
+	}
+}
+
+// resolveCredentials returns credentials unchanged if already set, otherwise
+// looks up "user:password" for remote's host in ~/.artie/auth.json, mirroring
+// how Docker resolves registry credentials from its own config file.
+func (remote *OCIRemote) resolveCredentials(credentials string) string {
+	if credentials != "" {
+		return credentials
+	}
+	u, err := url.Parse(remote.Uri)
+	if err != nil {
+		return credentials
+	}
+	found, err := core.HostCredentials(u.Host)
+	if err != nil || found == "" {
+		return credentials
+	}
+	return found
+}
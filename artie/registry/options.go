@@ -0,0 +1,42 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+package registry
+
+// TransferOptions controls how Push and Pull talk to a remote registry.
+type TransferOptions struct {
+	// Insecure skips TLS certificate verification when true. TLS verification
+	// is otherwise always enforced.
+	Insecure bool
+	// Quiet silences the progress reporter printed to stderr.
+	Quiet bool
+	// ChunkSize is the number of bytes requested per ranged GET when pulling
+	// the zip blob. Defaults to DefaultChunkSize when zero.
+	ChunkSize int64
+	// Parallelism is the number of chunks downloaded concurrently when
+	// pulling the zip blob. Defaults to DefaultParallelism when zero.
+	Parallelism int
+}
+
+const (
+	// DefaultChunkSize is used when TransferOptions.ChunkSize is not set.
+	DefaultChunkSize int64 = 8 * 1024 * 1024
+	// DefaultParallelism is used when TransferOptions.Parallelism is not set.
+	DefaultParallelism = 4
+)
+
+// withDefaults returns a copy of opts with zero-valued fields replaced by
+// their defaults.
+func (opts TransferOptions) withDefaults() TransferOptions {
+	if opts.ChunkSize <= 0 {
+		opts.ChunkSize = DefaultChunkSize
+	}
+	if opts.Parallelism <= 0 {
+		opts.Parallelism = DefaultParallelism
+	}
+	return opts
+}
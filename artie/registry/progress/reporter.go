@@ -0,0 +1,127 @@
+/*
+  Onix Config Manager - Artie
+  Copyright (c) 2018-2020 by www.gatblau.org
+  Licensed under the Apache License, Version 2.0 at http://www.apache.org/licenses/LICENSE-2.0
+  Contributors to this project, hereby assign copyright in this code to the project,
+  to be licensed under the same terms as the rest of the code.
+*/
+
+// Package progress reports the throughput of a Push or Pull to stderr, so
+// that transferring a large artefact does not leave the user staring at a
+// silent terminal.
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+)
+
+// Reporter tracks bytes transferred against a known total and periodically
+// prints progress to stderr. It is safe to use with Quiet set to true, in
+// which case it becomes a no-op. Add may be called concurrently, so a single
+// Reporter can be shared across parallel chunk downloads.
+type Reporter struct {
+	Label string
+	Total int64
+	Quiet bool
+
+	mu          sync.Mutex
+	transferred int64
+	started     time.Time
+	lastPrint   time.Time
+}
+
+// NewReporter creates a Reporter for a transfer of total bytes labelled
+// label (typically the artefact name). Progress is only printed when stdout
+// is a terminal and quiet is false.
+func NewReporter(label string, total int64, quiet bool) *Reporter {
+	return &Reporter{
+		Label: label,
+		Total: total,
+		Quiet: quiet || !isatty.IsTerminal(os.Stdout.Fd()),
+	}
+}
+
+// Add records n additional bytes transferred and prints progress, throttled
+// to at most once every 200ms. Safe for concurrent use.
+func (p *Reporter) Add(n int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.transferred += n
+	if p.Quiet {
+		return
+	}
+	if p.started.IsZero() {
+		p.started = time.Now()
+	}
+	now := time.Now()
+	if now.Sub(p.lastPrint) < 200*time.Millisecond && p.transferred < p.Total {
+		return
+	}
+	p.lastPrint = now
+	p.print()
+}
+
+// Done prints a final, complete progress line and a trailing newline.
+func (p *Reporter) Done() {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.Quiet {
+		return
+	}
+	p.transferred = p.Total
+	p.print()
+	fmt.Fprintln(os.Stderr)
+}
+
+func (p *Reporter) print() {
+	elapsed := time.Since(p.started)
+	throughput := float64(p.transferred) / elapsed.Seconds()
+	if elapsed <= 0 {
+		throughput = 0
+	}
+	var eta time.Duration
+	if throughput > 0 && p.Total > p.transferred {
+		eta = time.Duration(float64(p.Total-p.transferred)/throughput) * time.Second
+	}
+	fmt.Fprintf(os.Stderr, "\r%s: %s/%s (%s/s) ETA %s    ",
+		p.Label, humanBytes(p.transferred), humanBytes(p.Total), humanBytes(int64(throughput)), eta.Round(time.Second))
+}
+
+// humanBytes renders n bytes using the nearest of B/KB/MB/GB/TB/PB/EB.
+func humanBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%dB", n)
+	}
+	div, exp := int64(unit), 0
+	for v := n / unit; v >= unit; v /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f%cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// Reader wraps r, reporting every Read to p.
+type Reader struct {
+	r io.Reader
+	p *Reporter
+}
+
+// NewReader wraps r so that every byte read through it is reported to p.
+func NewReader(r io.Reader, p *Reporter) *Reader {
+	return &Reader{r: r, p: p}
+}
+
+func (pr *Reader) Read(b []byte) (int, error) {
+	n, err := pr.r.Read(b)
+	if n > 0 {
+		pr.p.Add(int64(n))
+	}
+	return n, err
+}